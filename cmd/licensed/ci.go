@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arzkar/licensed/pkg/licensed"
+)
+
+// printCIPipeline prints a pipeline of the --provider CI system enforcing the
+// license header with check. The license and owner are read from the
+// project config file by the pipeline, rather than taken from the identity
+// of whoever generates it.
+func printCIPipeline() {
+	cfg, _, err := licensed.LoadConfig(projectDir)
+	if err != nil || configPath == "" || (cfg.Owner == "" && len(cfg.Owners) == 0) {
+		fatal("the ci command needs the owner in the project config file, see the init command", "dir", projectDir)
+	}
+	keyFiles := []string{filepath.ToSlash(relToProject(configPath))}
+	if _, err := os.Stat(filepath.Join(projectDir, ".licensed-ignore")); err == nil {
+		keyFiles = append(keyFiles, ".licensed-ignore")
+	}
+
+	pipeline, err := licensed.CIPipeline(ciProvider, ciInstall, "licensed check", keyFiles)
+	if err != nil {
+		fatal("invalid settings", "error", err)
+	}
+	fmt.Print(pipeline)
+}
+
+// relToProject returns path relative to the project directory, or as it is
+// if it lies outside
+func relToProject(path string) string {
+	rel, err := filepath.Rel(projectDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// ANSI escape sequences coloring the lines of a diff
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// useColor reports whether output to w is colored: only on a terminal, and
+// neither with --no-color, NO_COLOR nor a dumb terminal
+func useColor(w io.Writer) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// colorizeDiff colors the added lines of a unified diff green, the removed
+// lines red and the hunk headers cyan
+func colorizeDiff(diff string) string {
+	lines := strings.SplitAfter(diff, "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		text := strings.TrimSuffix(line, "\n")
+		color := ""
+		switch {
+		case strings.HasPrefix(text, "+++ "), strings.HasPrefix(text, "--- "):
+			color = colorBold
+		case strings.HasPrefix(text, "+"):
+			color = colorGreen
+		case strings.HasPrefix(text, "-"):
+			color = colorRed
+		case strings.HasPrefix(text, "@@"):
+			color = colorCyan
+		}
+		if color == "" || text == "" {
+			b.WriteString(line)
+			continue
+		}
+		b.WriteString(color + text + colorReset + line[len(text):])
+	}
+	return b.String()
+}
+
+// diffWriter colors the diffs written to it
+type diffWriter struct {
+	w io.Writer
+}
+
+func (d diffWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(d.w, colorizeDiff(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// diffOutput returns where diffs are written, coloring them on a terminal
+func diffOutput() io.Writer {
+	if useColor(messages) {
+		return diffWriter{w: progressWriter{w: messages}}
+	}
+	return progressWriter{w: messages}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/arzkar/licensed/pkg/licensed"
+)
+
+// commands are the subcommands offered by shell completion
+var commands = []string{"add", "check", "remove", "update", "fix", "detect", "init", "install-hook", "list", "stamp", "undo", "doctor", "watch", "deps", "sbom", "stats", "ci", "version", "migrate", "completion"}
+
+// printCompletion writes the completion script of the shell named by the
+// first argument to stdout. License names are completed by calling back
+// into licensed, so custom licenses of --license-dir are offered too.
+func printCompletion() {
+	shell := pflag.Arg(0)
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion()
+	case "zsh":
+		script = zshCompletion()
+	case "fish":
+		script = fishCompletion()
+	case "powershell":
+		script = powershellCompletion()
+	default:
+		fmt.Println("Usage: licensed completion bash|zsh|fish|powershell")
+		os.Exit(1)
+	}
+	fmt.Print(script)
+}
+
+// printLicenseNames lists the license names for the completion scripts
+func printLicenseNames() {
+	names, err := licensed.AvailableLicenses(licenseDir)
+	if err != nil {
+		os.Exit(1)
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+}
+
+// completionFlag is a visible flag of the command line
+type completionFlag struct {
+	name, shorthand, usage string
+	// value is set if the flag takes an argument
+	value bool
+}
+
+func completionFlags() []completionFlag {
+	var flags []completionFlag
+	pflag.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		flags = append(flags, completionFlag{
+			name:      f.Name,
+			shorthand: f.Shorthand,
+			usage:     f.Usage,
+			value:     f.Value.Type() != "bool" && f.NoOptDefVal == "",
+		})
+	})
+	return flags
+}
+
+// flagWords returns every flag spelling, e.g. "-l --license"
+func flagWords() string {
+	var words []string
+	for _, f := range completionFlags() {
+		if f.shorthand != "" {
+			words = append(words, "-"+f.shorthand)
+		}
+		words = append(words, "--"+f.name)
+	}
+	return strings.Join(words, " ")
+}
+
+func bashCompletion() string {
+	return `# bash completion for licensed, load with: source <(licensed completion bash)
+_licensed() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    case "$prev" in
+        -l|--license)
+            COMPREPLY=($(compgen -W "$(licensed __licenses 2>/dev/null)" -- "$cur"))
+            return ;;
+        --output)
+            COMPREPLY=($(compgen -W "text json sarif github" -- "$cur"))
+            return ;;
+        --position)
+            COMPREPLY=($(compgen -W "top after-docstring" -- "$cur"))
+            return ;;
+        --format)
+            COMPREPLY=($(compgen -W "spdx-json" -- "$cur"))
+            return ;;
+        --from)
+            COMPREPLY=($(compgen -W "addlicense license-eye" -- "$cur"))
+            return ;;
+        --provider)
+            COMPREPLY=($(compgen -W "github gitlab azure" -- "$cur"))
+            return ;;
+        --on-conflict)
+            COMPREPLY=($(compgen -W "ask replace keep-both skip fail" -- "$cur"))
+            return ;;
+        --dir|--license-dir)
+            COMPREPLY=($(compgen -d -- "$cur"))
+            return ;;
+    esac
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "` + flagWords() + `" -- "$cur"))
+    elif [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "` + strings.Join(commands, " ") + `" -- "$cur"))
+    else
+        COMPREPLY=($(compgen -f -- "$cur"))
+    fi
+}
+complete -o filenames -F _licensed licensed
+`
+}
+
+func zshCompletion() string {
+	return `#compdef licensed
+# zsh completion for licensed, load with: source <(licensed completion zsh)
+_licensed() {
+    case "${words[CURRENT-1]}" in
+        -l|--license)
+            compadd -- ${(f)"$(licensed __licenses 2>/dev/null)"}
+            return ;;
+        --output)
+            compadd -- text json sarif github
+            return ;;
+        --position)
+            compadd -- top after-docstring
+            return ;;
+        --format)
+            compadd -- spdx-json
+            return ;;
+        --from)
+            compadd -- addlicense license-eye
+            return ;;
+        --provider)
+            compadd -- github gitlab azure
+            return ;;
+        --on-conflict)
+            compadd -- ask replace keep-both skip fail
+            return ;;
+        --dir|--license-dir)
+            _directories
+            return ;;
+    esac
+    if [[ "${words[CURRENT]}" == -* ]]; then
+        compadd -- ` + flagWords() + `
+    elif (( CURRENT == 2 )); then
+        compadd -- ` + strings.Join(commands, " ") + `
+    else
+        _files
+    fi
+}
+compdef _licensed licensed
+`
+}
+
+func fishCompletion() string {
+	var b strings.Builder
+	b.WriteString("# fish completion for licensed, load with: licensed completion fish | source\n")
+	fmt.Fprintf(&b, "complete -c licensed -n __fish_use_subcommand -f -a '%s'\n", strings.Join(commands, " "))
+	for _, f := range completionFlags() {
+		line := "complete -c licensed -l " + f.name
+		if f.shorthand != "" {
+			line += " -s " + f.shorthand
+		}
+		if f.value {
+			line += " -r"
+		}
+		switch f.name {
+		case "license":
+			line += " -x -a '(licensed __licenses 2>/dev/null)'"
+		case "output":
+			line += " -x -a 'text json sarif github'"
+		case "position":
+			line += " -x -a 'top after-docstring'"
+		case "format":
+			line += " -x -a 'spdx-json'"
+		case "from":
+			line += " -x -a 'addlicense license-eye'"
+		case "provider":
+			line += " -x -a 'github gitlab azure'"
+		case "on-conflict":
+			line += " -x -a 'ask replace keep-both skip fail'"
+		}
+		line += " -d '" + strings.ReplaceAll(f.usage, "'", `\'`) + "'"
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func powershellCompletion() string {
+	var flags []string
+	for _, word := range strings.Fields(flagWords()) {
+		flags = append(flags, "'"+word+"'")
+	}
+	var names []string
+	for _, command := range commands {
+		names = append(names, "'"+command+"'")
+	}
+	return `# PowerShell completion for licensed, load with: licensed completion powershell | Out-String | Invoke-Expression
+Register-ArgumentCompleter -Native -CommandName licensed -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | ForEach-Object { $_.ToString() })
+    $prev = if ($wordToComplete) { $words[-2] } else { $words[-1] }
+    $candidates = switch -regex ($prev) {
+        '^(-l|--license)$' { @(licensed __licenses 2>$null) }
+        '^--output$' { @('text', 'json', 'sarif', 'github') }
+        '^--position$' { @('top', 'after-docstring') }
+        '^--format$' { @('spdx-json') }
+        '^--from$' { @('addlicense', 'license-eye') }
+        '^--provider$' { @('github', 'gitlab', 'azure') }
+        '^--on-conflict$' { @('ask', 'replace', 'keep-both', 'skip', 'fail') }
+        default {
+            if ($wordToComplete -like '-*') { @(` + strings.Join(flags, ", ") + `) }
+            elseif ($words.Count -le 2) { @(` + strings.Join(names, ", ") + `) }
+            else { @() }
+        }
+    }
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`
+}
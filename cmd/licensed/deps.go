@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// reportDependency is a dependency listed by the deps command
+type reportDependency struct {
+	Name        string `json:"name"`
+	Version     string `json:"version,omitempty"`
+	Ecosystem   string `json:"ecosystem"`
+	License     string `json:"license"`
+	LicenseFile string `json:"license_file,omitempty"`
+	Copyleft    bool   `json:"copyleft"`
+	Violation   string `json:"violation,omitempty"`
+}
+
+// listDependencies reports the license of every dependency of the project,
+// exiting with exitConflicts if one breaks the dependency policy
+func listDependencies() {
+	deps, err := newProcessor().Dependencies()
+	if err != nil {
+		fail("cannot read dependencies", "error", err)
+	}
+
+	var violations int
+	for _, dep := range deps {
+		if dep.Violation != "" {
+			violations++
+		}
+	}
+
+	if outputFormat == "json" || outputFormat == "sarif" {
+		report := []reportDependency{}
+		for _, dep := range deps {
+			report = append(report, reportDependency(dep))
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fail("cannot write report", "error", err)
+		}
+	} else {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, dep := range deps {
+			license := dep.License
+			if license == "" {
+				license = "no license file"
+			}
+			var notes string
+			if dep.Copyleft {
+				notes = "copyleft"
+			}
+			if dep.Violation != "" {
+				notes += " violation: " + dep.Violation
+			}
+			line := dep.Ecosystem + "\t" + dep.Name + "\t" + dep.Version + "\t" + license
+			if notes != "" {
+				line += "\t" + strings.TrimSpace(notes)
+			}
+			fmt.Fprintln(writer, line)
+		}
+		writer.Flush()
+		if violations > 0 {
+			fmt.Printf("%d of %d dependencies break the license policy\n", violations, len(deps))
+		}
+	}
+
+	if violations > 0 {
+		os.Exit(exitConflicts)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/arzkar/licensed/pkg/licensed"
+)
+
+// runDoctor prints the outcome of every check of the project setup with
+// hints for the problems found, exiting non-zero if one is an error
+func runDoctor() {
+	var failed bool
+	for _, diagnosis := range licensed.Diagnose(newOptions()) {
+		switch {
+		case diagnosis.Err == nil:
+			fmt.Printf("ok       %s\n", diagnosis.Check)
+			continue
+		case diagnosis.Warning:
+			fmt.Printf("warning  %s: %s\n", diagnosis.Check, diagnosis.Err)
+		default:
+			failed = true
+			fmt.Printf("error    %s: %s\n", diagnosis.Check, diagnosis.Err)
+		}
+		if diagnosis.Hint != "" {
+			fmt.Printf("         hint: %s\n", diagnosis.Hint)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
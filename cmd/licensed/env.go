@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// envPrefix starts the environment variables setting flags, e.g.
+// LICENSED_LICENSE for --license
+const envPrefix = "LICENSED_"
+
+// envIgnored are the flags without an environment variable: CI setups
+// commonly pin the tool by LICENSED_VERSION
+var envIgnored = map[string]bool{"version": true}
+
+// envName returns the environment variable of a flag
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// applyEnv sets the flags not given on the command line from their
+// environment variables, so that they take precedence over the
+// configuration files. Repeatable flags take one value per line.
+func applyEnv() error {
+	var err error
+	pflag.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Hidden || flag.Changed || envIgnored[flag.Name] {
+			return
+		}
+		value, ok := os.LookupEnv(envName(flag.Name))
+		if !ok {
+			return
+		}
+
+		values := []string{value}
+		if strings.HasSuffix(flag.Value.Type(), "Array") || strings.HasSuffix(flag.Value.Type(), "Slice") {
+			values = nil
+			for _, line := range strings.Split(value, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					values = append(values, line)
+				}
+			}
+		}
+		for _, value := range values {
+			if setErr := pflag.Set(flag.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid %s: %w", envName(flag.Name), setErr)
+				return
+			}
+		}
+	})
+	return err
+}
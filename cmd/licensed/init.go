@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arzkar/licensed/pkg/licensed"
+)
+
+// initProject asks for the license settings of the project, writes them to
+// .licensed.yaml and .licensed-ignore and creates the LICENSE file
+func initProject() {
+	// Never overwrite an existing configuration by accident
+	for _, name := range licensed.ConfigFileNames {
+		path := filepath.Join(projectDir, name)
+		if _, err := os.Stat(path); err == nil && !forceHook {
+			fatal("config file already exists, use --force to replace it", "path", path)
+		}
+	}
+
+	input := bufio.NewReader(os.Stdin)
+	ask := func(question, fallback string) string {
+		if fallback != "" {
+			question += " [" + fallback + "]"
+		}
+		fmt.Printf("%s: ", question)
+		answer, err := input.ReadString('\n')
+		if err == io.EOF {
+			fmt.Println()
+		}
+		if answer = strings.TrimSpace(answer); answer == "" {
+			return fallback
+		}
+		return answer
+	}
+
+	var cfg licensed.Config
+	defaultLicense := licenseName
+	if defaultLicense == "" {
+		defaultLicense = "mit"
+	}
+	for {
+		cfg.License = ask("License (name or SPDX expression, see licensed list)", defaultLicense)
+		processor, err := licensed.New(licensed.Options{License: cfg.License, Dir: projectDir, LicenseDir: licenseDir, Offline: offline})
+		if err == nil {
+			// The copyright holder is only asked for next
+			_, err = processor.LicenseText()
+		}
+		if err == nil || errors.Is(err, licensed.ErrUnresolvedPlaceholder) {
+			break
+		}
+		var unknown *licensed.UnknownLicenseError
+		if errors.As(err, &unknown) {
+			fmt.Printf("%s\nAvailable licenses: %s\n", unknown, strings.Join(unknown.Available, ", "))
+			continue
+		}
+		fmt.Printf("Invalid license %s: %s\n", cfg.License, err)
+	}
+
+	var defaultOwner string
+	if len(owners) > 0 {
+		defaultOwner = owners[0].Name
+	}
+	cfg.Owner = ask("Copyright holder", defaultOwner)
+	if cfg.Owner == "" {
+		fatal("a copyright holder is required")
+	}
+
+	// Only record the header style if it differs from the full license text
+	switch strings.ToLower(ask("Header style: [s]pdx tag, [f]ull license text or [r]euse", "s")) {
+	case "s", "spdx":
+		cfg.SPDX = &[]bool{true}[0]
+	case "r", "reuse":
+		cfg.REUSE = &[]bool{true}[0]
+	}
+
+	var patterns []string
+	for _, pattern := range strings.Split(ask("Extra paths to ignore, comma separated (e.g. docs/, *.min.css)", ""), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	path, err := licensed.WriteConfig(projectDir, cfg)
+	if err != nil {
+		fail("cannot write config file", "path", path, "error", err)
+	}
+	logger.Info("wrote file", "path", path)
+
+	if len(patterns) > 0 {
+		path, err := addIgnorePatterns(patterns)
+		if err != nil {
+			fail("cannot write ignore file", "path", path, "error", err)
+		}
+		logger.Info("wrote file", "path", path)
+	}
+
+	// Create the license file from the answers
+	processor, err := licensed.New(licensed.Options{
+		License:    cfg.License,
+		Owners:     []licensed.Owner{{Name: cfg.Owner}},
+		Year:       year,
+		Dir:        projectDir,
+		LicenseDir: licenseDir,
+		Offline:    offline,
+	})
+	if err != nil {
+		fatal("invalid settings", "error", err)
+	}
+	if licenseFile == "" {
+		licenseFile = filepath.Join(projectDir, "LICENSE")
+	}
+	written, err := processor.WriteLicenseFiles(licenseFile)
+	if err != nil {
+		fail("cannot write license file", "path", licenseFile, "error", err)
+	}
+	for _, path := range written {
+		logger.Info("wrote file", "path", path)
+	}
+	fmt.Println("Run licensed add to add the license headers.")
+}
+
+// addIgnorePatterns appends the patterns missing from the .licensed-ignore
+// file of the project, creating it if needed
+func addIgnorePatterns(patterns []string) (string, error) {
+	path := filepath.Join(projectDir, ".licensed-ignore")
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return path, err
+	}
+
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(string(content), "\n") {
+		existing[strings.TrimSpace(line)] = true
+	}
+	newContent := string(content)
+	if newContent != "" && !strings.HasSuffix(newContent, "\n") {
+		newContent += "\n"
+	}
+	for _, pattern := range patterns {
+		if !existing[pattern] {
+			newContent += pattern + "\n"
+		}
+	}
+	return path, licensed.AtomicWriteFile(path, []byte(newContent), 0644)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/arzkar/licensed/pkg/licensed"
+)
+
+// logger reports progress, warnings and errors on stderr, keeping stdout for
+// the summary and reports
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// setupLogger levels the logger by --verbose and --quiet and formats it by
+// --log-format
+func setupLogger() {
+	level := slog.LevelInfo
+	switch {
+	case verbose:
+		level = slog.LevelDebug
+	case quiet:
+		level = slog.LevelWarn
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch logFormat {
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(progressWriter{w: os.Stderr}, opts))
+	case "text":
+		// Timestamps only clutter the output of a short run
+		opts.ReplaceAttr = func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) == 0 && attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		}
+		logger = slog.New(slog.NewTextHandler(progressWriter{w: os.Stderr}, opts))
+	default:
+		fatal("unknown log format", "format", logFormat)
+	}
+}
+
+// fatal logs an error in the flags, settings or configuration and exits
+func fatal(msg string, args ...any) {
+	exit(exitUsage, msg, args...)
+}
+
+// fail logs an error reading or writing files and exits, unless the error
+// stems from the settings, such as an unknown license
+func fail(msg string, args ...any) {
+	code := exitFileErrors
+	for _, arg := range args {
+		var unknown *licensed.UnknownLicenseError
+		if err, ok := arg.(error); ok && (errors.As(err, &unknown) || errors.Is(err, licensed.ErrUnresolvedPlaceholder)) {
+			code = exitUsage
+		}
+	}
+	exit(code, msg, args...)
+}
+
+// exit logs an error and exits with code. An unknown license is reported on
+// its own with the licenses available instead.
+func exit(code int, msg string, args ...any) {
+	for _, arg := range args {
+		var unknown *licensed.UnknownLicenseError
+		if err, ok := arg.(error); ok && errors.As(err, &unknown) {
+			msg, args = unknown.Error(), []any{"available", strings.Join(unknown.Available, ", ")}
+			break
+		}
+	}
+	logger.Error(msg, args...)
+	os.Exit(code)
+}
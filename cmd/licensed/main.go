@@ -0,0 +1,1054 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/arzkar/licensed/pkg/licensed"
+)
+
+var (
+	licenseName      string
+	userNames        []string
+	ownerEmails      []string
+	noticeEntries    []string
+	allowLicenses    []string
+	denyLicenses     []string
+	nodeModules      bool
+	projectName      string
+	organization     string
+	projectURL       string
+	owners           []licensed.Owner
+	year             string
+	listLicenses     bool
+	showVersion      bool
+	projectDir       string
+	projectDirs      []string
+	cacheFile        string
+	noCache          bool
+	noUserConfig     bool
+	noColor          bool
+	noProgress       bool
+	watchInterval    time.Duration
+	blankLines       int
+	checkOnly        bool
+	stagedOnly       bool
+	changedSince     string
+	installHook      bool
+	forceHook        bool
+	preCommitConf    bool
+	command          string
+	licenseDir       string
+	offline          bool
+	assumeYes        bool
+	noPrompt         bool
+	failOnConflict   bool
+	onConflict       string
+	preserveNotice   bool
+	fixHeaders       bool
+	dedupe           bool
+	dryRun           bool
+	wrapWidth        int
+	position         string
+	checksum         bool
+	strict           bool
+	templateFile     string
+	licenseTextFile  string
+	headerFile       string
+	spdxHeader       bool
+	fullText         bool
+	copyrightOnly    bool
+	reuseMode        bool
+	noGitignore      bool
+	noDefaultIgnores bool
+	trackedOnly      bool
+	forceComment     string
+	includeGenerated bool
+	includeHidden    bool
+	noEditorConfig   bool
+	indentBlocks     bool
+	followSymlinks   bool
+	maxFileSize      string
+	yearFromGit      bool
+	licenseFile      string
+	outputFormat     string
+	sbomFormat       string
+	statsDepth       int
+	ciProvider       string
+	ciInstall        string
+	migrateFrom      string
+	jsonOutput       bool
+	noLicenseFile    bool
+	quiet            bool
+	verbose          bool
+	logFormat        string
+	excludePatterns  []string
+	includePatterns  []string
+	filesFrom        string
+	stampLang        string
+	backup           bool
+	forceWritable    bool
+
+	// messages receives prompts and diffs, which go to stderr when stdout
+	// carries a JSON or SARIF report
+	messages io.Writer = os.Stdout
+
+	// configPath is the project config file read, "" if there is none
+	configPath string
+	// multiRoot tells that the project directories to process, projectDirs,
+	// are other than the one the configuration was read from
+	multiRoot bool
+
+	configIgnorePatterns  []string
+	configCommentSyntaxes map[string]licensed.CommentSyntax
+	configOverrides       []licensed.PathOverride
+	configPolicy          []licensed.PolicyRule
+	configHandlers        []licensed.FileHandler
+)
+
+func init() {
+	pflag.StringVarP(&licenseName, "license", "l", "", "license name (default: the license of an existing LICENSE or COPYING file, which is left as it is)")
+	pflag.StringArrayVarP(&userNames, "name", "n", nil, "copyright holder, repeat for several (default: $GIT_AUTHOR_NAME or git config user.name)")
+	pflag.StringArrayVar(&ownerEmails, "owner-email", nil, "email of the copyright holder for [email], repeat for several in the order of --name")
+	pflag.StringVar(&projectName, "project", "", "project name for [project] (default: the name of the project directory)")
+	pflag.StringVar(&organization, "organization", "", "organization behind the project for [organization]")
+	pflag.StringVar(&projectURL, "url", "", "project URL for [url]")
+	pflag.StringVarP(&year, "year", "y", "", "year (default: the current year)")
+	pflag.BoolVar(&listLicenses, "list", false, "list all supported licenses")
+	pflag.BoolVarP(&showVersion, "version", "V", false, "print the version, commit and build date")
+	pflag.StringArrayVar(&projectDirs, "dir", []string{"."}, "path to the project directory, repeat or use a glob such as 'services/*' for several sharing one summary and cache")
+	pflag.BoolVarP(&assumeYes, "yes", "Y", false, "add the header to files with a different license header without prompting")
+	pflag.BoolVar(&noPrompt, "no-prompt", false, "skip files with a different license header without prompting")
+	pflag.BoolVar(&fixHeaders, "fix", false, "rewrite outdated headers of the same license in place, e.g. with an old company name, instead of treating them as different headers")
+	pflag.BoolVar(&dedupe, "dedupe", false, "with fix, collapse license headers stacked at the top of files by earlier runs into one")
+	pflag.BoolVar(&failOnConflict, "fail-on-conflict", false, "leave files with a different license header and exit with status 3")
+	pflag.BoolVar(&preserveNotice, "preserve-original-notice", false, "with --on-conflict replace, keep the replaced header below the new one as the original notice")
+	pflag.StringVar(&onConflict, "on-conflict", "", "what to do with files with a different license header: ask, replace it, keep-both with the new header above, skip, or fail with status 3 (default: ask, failing like fail without a terminal, or the strategy of --yes, --no-prompt or --fail-on-conflict)")
+	pflag.StringVar(&outputFormat, "output", "text", "output format: text, json, sarif or github for GitHub Actions annotations")
+	pflag.BoolVar(&jsonOutput, "json", false, "shorthand for --output json")
+	pflag.IntVar(&statsDepth, "depth", 1, "directory levels the stats command breaks the coverage down by")
+	pflag.StringVar(&ciProvider, "provider", "github", "CI system the ci command writes a pipeline for: github, gitlab or azure")
+	pflag.StringVar(&ciInstall, "install", licensed.DefaultCIInstall, "command installing licensed in the pipeline of the ci command")
+	pflag.StringVar(&sbomFormat, "format", "spdx-json", "document format of the sbom command: spdx-json")
+	pflag.StringVar(&migrateFrom, "from", "", "tool whose configuration the migrate command converts: addlicense or license-eye")
+	pflag.BoolVarP(&quiet, "quiet", "q", false, "only log warnings and errors")
+	pflag.BoolVarP(&verbose, "verbose", "v", false, "also log debug messages, such as every file processed")
+	pflag.StringVar(&logFormat, "log-format", "text", "format of the messages logged to stderr: text or json")
+	pflag.BoolVar(&backup, "backup", false, "save the originals of changed files to "+licensed.BackupDir+" for the undo command")
+	pflag.BoolVar(&forceWritable, "force-writable", false, "modify read-only files, restoring their permissions afterwards, instead of reporting them as read-only")
+	pflag.BoolVar(&dryRun, "dry-run", false, "print a diff of the changes instead of writing files")
+	pflag.BoolVar(&noColor, "no-color", false, "never color diffs, which are colored on a terminal unless NO_COLOR is set")
+	pflag.BoolVar(&noProgress, "no-progress", false, "never show the count of files processed, which is shown on stderr when it is a terminal")
+	pflag.BoolVar(&checksum, "checksum", false, "end headers with a checksum marker of their text, verified by check --strict")
+	pflag.BoolVar(&strict, "strict", false, "with check, also fail on headers not matching their checksum marker")
+	pflag.StringVar(&position, "position", licensed.PositionTop, "where the header goes: top, or after-docstring to keep a Python module docstring or file-level Javadoc above it")
+	pflag.IntVar(&wrapWidth, "width", 80, "column at which header lines are wrapped, 0 to disable wrapping")
+	pflag.StringVar(&licenseTextFile, "license-text-file", "", "plain text file with a custom license body, e.g. a proprietary EULA, used for the header and the license file instead of the catalog text")
+	pflag.StringVar(&headerFile, "header-file", "", "file with the exact header text, commented out as is without filling in placeholders, and required byte for byte by check")
+	pflag.StringVar(&templateFile, "template", "", "text/template file used for the header instead of the license text, with {{.Year}}, {{.Owner}}, {{.Email}}, {{.License}}, {{.SPDXID}}, {{.Project}}, {{.Organization}}, {{.URL}} and {{.Filename}}")
+	pflag.BoolVar(&spdxHeader, "spdx", false, "use a short SPDX-License-Identifier header instead of the license notice")
+	pflag.BoolVar(&fullText, "full-text", false, "use the full license text in headers even for licenses with a short notice for them, such as Apache-2.0 and the GPL")
+	pflag.BoolVar(&copyrightOnly, "copyright-only", false, "use a header of just the copyright line, e.g. Copyright (c) 2025 ACME Inc. All rights reserved., needing no license")
+	pflag.BoolVar(&reuseMode, "reuse", false, "follow the REUSE specification: SPDX tags, LICENSES/ texts and .reuse/dep5 for files without a header")
+	pflag.StringArrayVar(&excludePatterns, "exclude", nil, "gitignore-style pattern of files to skip, repeat for several")
+	pflag.StringArrayVar(&includePatterns, "include", nil, "gitignore-style pattern restricting the run to the matching files, repeat for several")
+	pflag.BoolVar(&noGitignore, "no-gitignore", false, "do not skip files ignored by .gitignore")
+	pflag.BoolVar(&noDefaultIgnores, "no-default-ignores", false, "do not skip node_modules, nor vendor, dist, build and target at the top of the project")
+	pflag.BoolVar(&noEditorConfig, "no-editorconfig", false, "ignore the end_of_line and charset of .editorconfig files when inserting headers")
+	pflag.BoolVar(&indentBlocks, "editorconfig-indent", false, "indent the lines inside block comments without decoration, such as HTML's, as .editorconfig declares")
+	pflag.BoolVar(&trackedOnly, "tracked-only", true, "in a git work tree, only process the files git tracks, listed by git ls-files; --tracked-only=false also processes untracked files")
+	pflag.BoolVar(&includeHidden, "include-hidden", false, "process dotfiles and the files of dot-directories such as .github/ and .vscode/, which are skipped otherwise")
+	pflag.BoolVar(&includeGenerated, "include-generated", false, "process generated files, which are skipped otherwise")
+	pflag.StringVar(&maxFileSize, "max-file-size", "1MiB", "skip files larger than this size, e.g. 500KiB or 10MB, 0 to disable the limit")
+	pflag.BoolVar(&followSymlinks, "follow-symlinks", false, "walk into linked directories and process linked files, which are skipped otherwise")
+	pflag.StringVar(&forceComment, "force-comment", "", "line comment prefix for files with unrecognized extensions, which are skipped otherwise")
+	pflag.Lookup("force-comment").NoOptDefVal = "//"
+	pflag.BoolVar(&yearFromGit, "year-from-git", false, "use each file's first and last commit years from git history")
+	pflag.BoolVar(&offline, "offline", false, "never download license texts missing from the bundled catalog")
+	pflag.StringArrayVar(&noticeEntries, "notice", nil, "attribution entry for the NOTICE file written along with the license file of licenses such as Apache-2.0, repeat for several")
+	pflag.StringArrayVar(&allowLicenses, "allow-license", nil, "license the deps command allows dependencies to use, all others are violations, repeat for several")
+	pflag.StringArrayVar(&denyLicenses, "deny-license", nil, "license the deps command reports as a violation, or copyleft for every copyleft license, repeat for several")
+	pflag.BoolVar(&nodeModules, "node-modules", false, "also report the packages in node_modules with the deps command")
+	pflag.StringVar(&licenseFile, "license-file", "", "path the license text is written to (default: LICENSE in the project directory)")
+	pflag.BoolVar(&noLicenseFile, "no-license-file", false, "do not write the license text to a file")
+	pflag.StringVar(&licenseDir, "license-dir", "", "directory with custom license texts overriding the bundled ones")
+	pflag.StringVar(&cacheFile, "cache", "", "path to the cache of unchanged files skipped by later runs (default: "+licensed.DefaultCacheFile+" in the project directory)")
+	pflag.DurationVar(&watchInterval, "interval", time.Second, "how long the watch command waits for new files to stop changing before stamping them")
+	pflag.BoolVar(&noUserConfig, "no-user-config", false, "ignore the personal defaults of "+filepath.Join("~", ".config", "licensed", "config.yaml"))
+	pflag.BoolVar(&noCache, "no-cache", false, "check every file, neither reading nor writing the cache")
+	pflag.IntVar(&blankLines, "blank-lines", 1, "number of blank lines between the license header and the code")
+	pflag.BoolVar(&checkOnly, "check", false, "report files missing the license header without modifying them")
+	pflag.StringVar(&stampLang, "lang", "", "file extension selecting the comment syntax of the stamp command, e.g. go")
+	pflag.StringVar(&filesFrom, "files-from", "", "file listing the files to process, one per line, or - for stdin")
+	pflag.BoolVar(&stagedOnly, "staged", false, "only process files staged for commit")
+	pflag.BoolVar(&stagedOnly, "staged-only", false, "only process files staged for commit")
+	pflag.CommandLine.MarkHidden("staged-only")
+	pflag.StringVar(&changedSince, "changed", "", "only process files changed since the merge base of this git ref and HEAD, e.g. origin/main")
+	pflag.BoolVar(&installHook, "install-hook", false, "install a git pre-commit hook that checks staged files")
+	pflag.CommandLine.MarkHidden("install-hook")
+	pflag.BoolVar(&forceHook, "force", false, "overwrite an existing pre-commit hook not installed by licensed, or the configuration with init")
+	pflag.BoolVar(&preCommitConf, "pre-commit-config", false, "add the hook to .pre-commit-config.yaml instead of .git/hooks")
+
+	pflag.Usage = printUsage
+	// Flag errors exit with exitUsage rather than pflag's 2, which reads as
+	// missing headers
+	pflag.CommandLine.Init(os.Args[0], pflag.ContinueOnError)
+
+	// The first argument selects a subcommand unless it is a flag
+	args := os.Args[1:]
+	command = "add"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = args[0]
+		args = args[1:]
+	}
+	parseErr := pflag.CommandLine.Parse(args)
+	if errors.Is(parseErr, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	envErr := applyEnv()
+	setupLogger()
+	if parseErr != nil {
+		fatal("invalid flags, see licensed --help", "error", parseErr)
+	}
+	if envErr != nil {
+		fatal("invalid environment variable", "error", envErr)
+	}
+
+	// Keep stdout for the report with a machine-readable output format
+	if jsonOutput {
+		outputFormat = "json"
+	}
+	switch outputFormat {
+	case "text", "github":
+	case "json", "sarif":
+		messages = os.Stderr
+	default:
+		fatal("unknown output format", "format", outputFormat)
+	}
+
+	// The version is printed even with a broken configuration
+	if showVersion {
+		command = "version"
+	}
+	if command == "version" {
+		return
+	}
+
+	// Read the user and project configuration files, letting the project
+	// override the user defaults and flags override both, unless the doctor
+	// command is to report the error
+	var userCfg licensed.Config
+	if !noUserConfig {
+		var userCfgPath string
+		var err error
+		userCfg, userCfgPath, err = licensed.LoadUserConfig()
+		if err != nil && command != "doctor" {
+			fatal("cannot read user config file", "path", userCfgPath, "error", err)
+		}
+	}
+	dirs, err := expandDirs(projectDirs)
+	if err != nil {
+		fatal("invalid project directory", "error", err)
+	}
+	projectDir, projectDirs = dirs[0], dirs
+	cfg, cfgPath, err := licensed.LoadConfig(projectDir)
+	if err != nil && command != "doctor" {
+		fatal("cannot read config file", "path", cfgPath, "error", err)
+	}
+	configPath = cfgPath
+	applyConfig(userCfg.Merge(cfg))
+
+	// Several project directories share the configuration and the cache of
+	// the first, or of the one whose configuration lists them
+	if len(cfg.Dirs) > 0 && !pflag.CommandLine.Changed("dir") {
+		dirs = nil
+		for _, dir := range cfg.Dirs {
+			dirs = append(dirs, resolveProjectPath(dir))
+		}
+		if projectDirs, err = expandDirs(dirs); err != nil {
+			fatal("invalid project directory", "path", cfgPath, "error", err)
+		}
+	}
+	multiRoot = len(projectDirs) > 1 || filepath.Clean(projectDirs[0]) != filepath.Clean(projectDir)
+
+	// --yes, --no-prompt and --fail-on-conflict are shorthands for conflict
+	// strategies
+	if onConflict == "" {
+		switch {
+		case failOnConflict:
+			onConflict = licensed.ConflictFail
+		case noPrompt:
+			onConflict = licensed.ConflictSkip
+		case assumeYes:
+			onConflict = licensed.ConflictKeepBoth
+		case command == "stamp":
+			// stdin holds the content to stamp, not answers
+			onConflict = licensed.ConflictSkip
+		default:
+			onConflict = licensed.ConflictAsk
+		}
+	}
+	// Without a terminal to ask on, files with a different header are left
+	// as they are and fail the run instead of passing unnoticed
+	if onConflict == licensed.ConflictFail || (onConflict == licensed.ConflictAsk && !isTerminal(os.Stdin)) {
+		failOnConflict = true
+	}
+
+	// Without a license set, stamp that of the existing license file
+	if !headerConfigured() {
+		switch command {
+		case "add", "check", "install-hook", "remove", "update", "fix", "detect", "init", "watch", "stamp", "sbom", "stats", "ci":
+			inferLicense()
+		}
+	}
+
+	// Default the name to the git author and the year to the current one
+	for _, name := range userNames {
+		owners = append(owners, licensed.Owner{Name: name})
+	}
+	if len(owners) == 0 {
+		name := os.Getenv("GIT_AUTHOR_NAME")
+		if name == "" {
+			name = licensed.GitUserName(projectDir)
+		}
+		if name != "" {
+			owners = append(owners, licensed.Owner{Name: name})
+		}
+	}
+	if len(ownerEmails) > len(owners) {
+		fatal("more --owner-email than copyright holders")
+	}
+	for i, email := range ownerEmails {
+		owners[i].Email = email
+	}
+	if year == "" {
+		year = strconv.Itoa(time.Now().Year())
+	}
+	if noCache {
+		cacheFile = ""
+	} else if cacheFile == "" {
+		cacheFile = filepath.Join(projectDir, licensed.DefaultCacheFile)
+	}
+}
+
+func main() {
+	if multiRoot {
+		switch command {
+		case "add", "check", "remove", "update", "fix", "list", "completion", "__licenses":
+		default:
+			fatal("the command takes a single project directory", "command", command, "dirs", projectDirs)
+		}
+		if len(fileArgs()) > 0 {
+			fatal("file arguments take a single project directory", "dirs", projectDirs)
+		}
+	}
+
+	switch command {
+	case "add":
+	case "check":
+		checkOnly = true
+	case "install-hook":
+		installHook = true
+	case "list":
+		listLicenses = true
+	case "remove":
+		removeHeaders()
+		return
+	case "update":
+		updateHeaders()
+		return
+	case "fix":
+		fixHeaderCopies()
+		return
+	case "detect":
+		detectLicenses()
+		return
+	case "stamp":
+		stampStdin()
+		return
+	case "undo":
+		undoChanges()
+		return
+	case "init":
+		initProject()
+		return
+	case "migrate":
+		migrateConfig()
+		return
+	case "completion":
+		printCompletion()
+		return
+	case "doctor":
+		runDoctor()
+		return
+	case "watch":
+		watchFiles()
+		return
+	case "deps":
+		listDependencies()
+		return
+	case "sbom":
+		writeSBOM()
+		return
+	case "stats":
+		printStats()
+		return
+	case "ci":
+		printCIPipeline()
+		return
+	case "version":
+		printVersion()
+		return
+	case "__licenses":
+		printLicenseNames()
+		return
+	default:
+		logger.Error("unknown command", "command", command)
+		printUsage()
+		os.Exit(exitUsage)
+	}
+
+	if listLicenses {
+		fetchLicenses()
+	}
+
+	if !headerConfigured() || len(owners) == 0 || projectDir == "" || blankLines < 0 {
+		printUsage()
+		os.Exit(exitUsage)
+	}
+
+	if installHook {
+		installPreCommitHook()
+		return
+	}
+
+	if checkOnly {
+		result := forEachRoot((*licensed.Processor).Check)
+		writeReport(result)
+		printSummary(result)
+
+		// Fail if any file is missing the license header
+		exitWith(result)
+		return
+	}
+
+	result := forEachRoot(addHeaders)
+	writeReport(result)
+	printSummary(result)
+	exitWith(result)
+}
+
+// inferLicense sets the license to that of the LICENSE or COPYING file of
+// the project, which is left as it is rather than rewritten
+func inferLicense() {
+	license, path, err := licensed.DetectProjectLicense(projectDir, licenseDir)
+	if err != nil {
+		logger.Debug("cannot detect the license of the project", "error", err)
+		return
+	}
+	if license == "" {
+		if path != "" {
+			logger.Debug("license file resembles no known license", "path", path)
+		}
+		return
+	}
+	logger.Info("using the license of the existing license file", "license", license, "path", path)
+	licenseName = license
+	noLicenseFile = true
+}
+
+// addHeaders adds the license header to the files of the project of
+// processor and writes its license and notice files
+func addHeaders(processor *licensed.Processor) (*licensed.Result, error) {
+	result, err := processor.Add()
+	if err != nil || dryRun {
+		return result, err
+	}
+	for _, filePath := range result.Changed {
+		logger.Info("added license header", "path", filePath)
+	}
+	for _, filePath := range result.Generated {
+		logger.Info("wrote file", "path", filePath)
+	}
+
+	// Write the license content to the license file, or one file per
+	// license of a license expression
+	if (licenseName != "" || licenseTextFile != "") && !noLicenseFile {
+		path := licenseFile
+		if path == "" {
+			path = filepath.Join(projectDir, "LICENSE")
+		}
+		if _, err := processor.WriteLicenseFiles(path); err != nil {
+			logger.Error("cannot write license file", "path", path, "error", err)
+			result.Errors = append(result.Errors, licensed.Problem{Path: path, Message: err.Error()})
+		}
+
+		// Licenses such as Apache-2.0 pass attributions on in a NOTICE file
+		if processor.NeedsNotice() || len(noticeEntries) > 0 {
+			noticeFile := filepath.Join(projectDir, "NOTICE")
+			if err := processor.WriteNoticeFile(noticeFile); err != nil {
+				logger.Error("cannot write notice file", "path", noticeFile, "error", err)
+				result.Errors = append(result.Errors, licensed.Problem{Path: noticeFile, Message: err.Error()})
+			}
+		}
+	}
+	return result, nil
+}
+
+// installPreCommitHook sets up a pre-commit hook that blocks commits of
+// files lacking the license header
+func installPreCommitHook() {
+	hookArgs := append([]string{"licensed", "check", "--staged"}, headerArgs()...)
+	hookCommand := strings.Join(hookArgs, " ")
+
+	if preCommitConf {
+		added, err := licensed.AddPreCommitConfig(projectDir, hookCommand)
+		if err != nil {
+			fail("cannot update pre-commit config", "path", licensed.PreCommitConfigFile, "error", err)
+		}
+		if !added {
+			logger.Info("pre-commit config already runs licensed", "path", licensed.PreCommitConfigFile)
+			return
+		}
+		logger.Info("hook added to pre-commit config", "path", licensed.PreCommitConfigFile)
+		return
+	}
+
+	err := licensed.InstallPreCommitHook(projectDir, hookCommand, forceHook)
+	if errors.Is(err, licensed.ErrHookExists) {
+		fatal("cannot install pre-commit hook, use --force to overwrite it", "error", err)
+	}
+	if err != nil {
+		fail("cannot install pre-commit hook", "error", err)
+	}
+	logger.Info("pre-commit hook installed")
+}
+
+// headerArgs returns the shell-quoted flags selecting the license header,
+// for commands run outside of this invocation. The owner and year are left
+// for them to take from the configuration or their defaults when they run,
+// e.g. the git user committing.
+func headerArgs() []string {
+	args := []string{"--license", licensed.ShellQuote(licenseName)}
+	if copyrightOnly {
+		args = append(args, "--copyright-only")
+	}
+	if licenseTextFile != "" {
+		args = append(args, "--license-text-file", licensed.ShellQuote(licenseTextFile))
+	}
+	if headerFile != "" {
+		args = append(args, "--header-file", licensed.ShellQuote(headerFile))
+	}
+	return args
+}
+
+// removeHeaders strips the license header from every file in the project
+func removeHeaders() {
+	result := forEachRoot((*licensed.Processor).Remove)
+	writeReport(result)
+
+	if !dryRun {
+		for _, filePath := range result.Changed {
+			logger.Info("removed license header", "path", filePath)
+		}
+	}
+	printSummary(result)
+	exitWith(result)
+}
+
+// fixHeaderCopies collapses the copies of the license header stacked at the
+// top of files into one
+func fixHeaderCopies() {
+	if !dedupe {
+		fatal("the fix command needs a repair mode", "modes", "--dedupe")
+	}
+
+	result := forEachRoot((*licensed.Processor).Dedupe)
+	writeReport(result)
+
+	if !dryRun {
+		for _, filePath := range result.Changed {
+			logger.Info("collapsed duplicate license headers", "path", filePath)
+		}
+	}
+	printSummary(result)
+	exitWith(result)
+}
+
+// updateHeaders bumps the copyright years in the license header of every
+// file in the project to the --year value
+func updateHeaders() {
+	target, err := strconv.Atoi(year)
+	if err != nil {
+		fatal("invalid year", "year", year)
+	}
+
+	result := forEachRoot(func(processor *licensed.Processor) (*licensed.Result, error) {
+		return processor.Update(target)
+	})
+	writeReport(result)
+
+	if !dryRun {
+		for _, filePath := range result.Changed {
+			logger.Info("updated copyright years", "path", filePath)
+		}
+	}
+	printSummary(result)
+	exitWith(result)
+}
+
+// watchFiles adds the license header to the files created in the project
+// until interrupted
+func watchFiles() {
+	if !headerConfigured() || len(owners) == 0 || watchInterval <= 0 {
+		printUsage()
+		os.Exit(exitUsage)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	logger.Info("watching for new files", "dir", projectDir, "interval", watchInterval)
+	err := newProcessor().Watch(ctx, watchInterval, func(result *licensed.Result) {
+		for _, filePath := range result.Changed {
+			logger.Info("added license header", "path", filePath)
+		}
+	})
+	if err != nil {
+		fail("cannot watch directory", "error", err)
+	}
+}
+
+// undoChanges restores the files changed by the last run with --backup
+func undoChanges() {
+	result, err := newProcessor().Undo()
+	if err != nil {
+		fail("cannot undo the last run", "error", err)
+	}
+	writeReport(result)
+
+	for _, filePath := range result.Changed {
+		logger.Info("restored file", "path", filePath)
+	}
+	exitWith(result)
+}
+
+// stampStdin writes the content read on stdin to stdout with the license
+// header added, leaving a different header in place unless --yes is set
+func stampStdin() {
+	// Keep stdout for the content
+	messages = os.Stderr
+	if stampLang == "" || !headerConfigured() || len(owners) == 0 {
+		fatal("usage: licensed stamp --lang <extension> -l <license> [flags] < in > out")
+	}
+	processor := newProcessor()
+	filePath := "stdin." + strings.TrimPrefix(stampLang, ".")
+	if !processor.Supports(filePath) {
+		fatal("unknown comment syntax, set one with --force-comment or under comments in the config file", "lang", stampLang)
+	}
+
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fail("cannot read stdin", "error", err)
+	}
+	output, err := processor.Stamp(content, filePath)
+	if err != nil {
+		fail("cannot add license header", "error", err)
+	}
+	os.Stdout.Write(output)
+}
+
+// detectLicenses reports the license found in the header of every file in
+// the project, failing if one differs from the configured license
+func detectLicenses() {
+	result, err := newProcessor().Detect()
+	if err != nil {
+		fail("cannot traverse directory", "error", err)
+	}
+	writeReport(result)
+
+	var mismatches int
+	for _, detection := range result.Detections {
+		if detection.Mismatch {
+			mismatches++
+		}
+		if outputFormat == "json" || outputFormat == "sarif" {
+			continue
+		}
+
+		switch {
+		case detection.License == "":
+			fmt.Printf("%s: no license header\n", detection.Path)
+		case detection.Mismatch:
+			fmt.Printf("%s: %s (%.0f%%), expected %s\n", detection.Path, detection.License, detection.Similarity*100, licensed.SPDXID(licenseName))
+		default:
+			fmt.Printf("%s: %s (%.0f%%)\n", detection.Path, detection.License, detection.Similarity*100)
+		}
+	}
+	exitWith(result)
+	if mismatches > 0 {
+		os.Exit(exitConflicts)
+	}
+}
+
+// writeSBOM prints the SPDX document describing the project files
+func writeSBOM() {
+	if sbomFormat != "spdx-json" {
+		fatal("unknown sbom format", "format", sbomFormat)
+	}
+	doc, err := newProcessor().SBOM()
+	if err != nil {
+		fail("cannot describe project", "error", err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		fail("cannot write sbom", "error", err)
+	}
+}
+
+// headerConfigured reports whether the flags and configuration select a
+// header: a license, a template, a license text file, a header file or
+// copyright-only
+func headerConfigured() bool {
+	return licenseName != "" || templateFile != "" || licenseTextFile != "" || headerFile != "" || copyrightOnly
+}
+
+// newProcessor builds a licensed.Processor from the flags and configuration
+func newProcessor() *licensed.Processor {
+	processor, err := licensed.New(newOptions())
+	if err != nil {
+		fatal("invalid settings", "error", err)
+	}
+	return processor
+}
+
+// newOptions returns the processor options set by the flags and
+// configuration
+func newOptions() licensed.Options {
+	return licensed.Options{
+		License:          licenseName,
+		Owners:           owners,
+		Year:             year,
+		Dir:              projectDir,
+		Template:         templateFile,
+		LicenseTextFile:  licenseTextFile,
+		HeaderFile:       headerFile,
+		SPDX:             spdxHeader,
+		FullText:         fullText,
+		CopyrightOnly:    copyrightOnly,
+		REUSE:            reuseMode,
+		LicenseDir:       licenseDir,
+		Offline:          offline,
+		BlankLines:       blankLines,
+		Width:            wrapWidth,
+		Position:         position,
+		Checksum:         checksum,
+		Project:          projectName,
+		Organization:     organization,
+		URL:              projectURL,
+		Strict:           strict,
+		Notice:           noticeEntries,
+		Dependencies:     licensed.DependencyPolicy{Allow: allowLicenses, Deny: denyLicenses, NodeModules: nodeModules},
+		Fix:              fixHeaders,
+		YearFromGit:      yearFromGit,
+		NoGitignore:      noGitignore,
+		NoDefaultIgnores: noDefaultIgnores,
+		TrackedOnly:      trackedOnly,
+		Files:            fileArgs(),
+		StagedOnly:       stagedOnly,
+		ChangedSince:     changedSince,
+		ForceComment:     forceComment,
+		IncludeGenerated: includeGenerated,
+		IncludeHidden:    includeHidden,
+		NoEditorConfig:   noEditorConfig,
+		IndentBlocks:     indentBlocks,
+		FollowSymlinks:   followSymlinks,
+		MaxFileSize:      parseSize(maxFileSize),
+		IgnorePatterns:   append(configIgnorePatterns, excludePatterns...),
+		IncludePatterns:  includePatterns,
+		CommentSyntaxes:  configCommentSyntaxes,
+		Overrides:        configOverrides,
+		Policy:           configPolicy,
+		Handlers:         configHandlers,
+		CacheFile:        cacheFile,
+		DryRun:           dryRun,
+		Backup:           backup,
+		ForceWritable:    forceWritable,
+		Diff:             diffOutput(),
+		Logger:           logger,
+		Progress:         progressFunc(),
+		OnConflict:       onConflict,
+		PreserveNotice:   preserveNotice,
+		Confirm:          confirmReplace,
+	}
+}
+
+// parseSize parses a size in bytes with an optional unit such as KB, KiB,
+// MB or MiB
+func parseSize(size string) int64 {
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+		{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+		{"B", 1},
+	}
+	number, factor := strings.TrimSpace(size), int64(1)
+	for _, unit := range units {
+		if len(number) > len(unit.suffix) && strings.EqualFold(number[len(number)-len(unit.suffix):], unit.suffix) {
+			number, factor = strings.TrimSpace(number[:len(number)-len(unit.suffix)]), unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		fatal("invalid --max-file-size", "size", size)
+	}
+	return int64(n * float64(factor))
+}
+
+// fileArgs returns the files given as arguments and listed by --files-from
+func fileArgs() []string {
+	files := pflag.Args()
+	if filesFrom == "" {
+		return files
+	}
+
+	var data []byte
+	var err error
+	if filesFrom == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filesFrom)
+	}
+	if err != nil {
+		fail("cannot read file list", "path", filesFrom, "error", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files
+}
+
+// applyConfig fills in the settings from the configuration file that were
+// not given as flags
+func applyConfig(cfg licensed.Config) {
+	flags := pflag.CommandLine
+	if cfg.License != "" && !flags.Changed("license") {
+		licenseName = cfg.License
+	}
+	if !flags.Changed("name") {
+		if cfg.Owner != "" {
+			owners = append(owners, licensed.Owner{Name: cfg.Owner, Email: cfg.Email})
+		}
+		owners = append(owners, cfg.Owners...)
+	}
+	if cfg.OnConflict != "" && !flags.Changed("on-conflict") && !flags.Changed("yes") && !flags.Changed("no-prompt") && !flags.Changed("fail-on-conflict") {
+		onConflict = cfg.OnConflict
+	}
+	if cfg.PreserveNotice != nil && !flags.Changed("preserve-original-notice") {
+		preserveNotice = *cfg.PreserveNotice
+	}
+	if cfg.Year != "" && !flags.Changed("year") {
+		year = cfg.Year
+	}
+	if len(cfg.Notice) > 0 && !flags.Changed("notice") {
+		noticeEntries = cfg.Notice
+	}
+	if len(cfg.Dependencies.Allow) > 0 && !flags.Changed("allow-license") {
+		allowLicenses = cfg.Dependencies.Allow
+	}
+	if len(cfg.Dependencies.Deny) > 0 && !flags.Changed("deny-license") {
+		denyLicenses = cfg.Dependencies.Deny
+	}
+	if cfg.Dependencies.NodeModules && !flags.Changed("node-modules") {
+		nodeModules = true
+	}
+	if cfg.Template != "" && !flags.Changed("template") {
+		templateFile = resolveProjectPath(cfg.Template)
+	}
+	if cfg.LicenseTextFile != "" && !flags.Changed("license-text-file") {
+		licenseTextFile = resolveProjectPath(cfg.LicenseTextFile)
+	}
+	if cfg.HeaderFile != "" && !flags.Changed("header-file") {
+		headerFile = resolveProjectPath(cfg.HeaderFile)
+	}
+	if cfg.TrackedOnly != nil && !flags.Changed("tracked-only") {
+		trackedOnly = *cfg.TrackedOnly
+	}
+	if cfg.CopyrightOnly != nil && !flags.Changed("copyright-only") {
+		copyrightOnly = *cfg.CopyrightOnly
+	}
+	if cfg.SPDX != nil && !flags.Changed("spdx") {
+		spdxHeader = *cfg.SPDX
+	}
+	if cfg.FullText != nil && !flags.Changed("full-text") {
+		fullText = *cfg.FullText
+	}
+	if cfg.REUSE != nil && !flags.Changed("reuse") {
+		reuseMode = *cfg.REUSE
+	}
+	if cfg.BlankLines != nil && !flags.Changed("blank-lines") {
+		blankLines = *cfg.BlankLines
+	}
+	if cfg.Width != nil && !flags.Changed("width") {
+		wrapWidth = *cfg.Width
+	}
+	if cfg.Position != "" && !flags.Changed("position") {
+		position = cfg.Position
+	}
+	if cfg.Project != "" && !flags.Changed("project") {
+		projectName = cfg.Project
+	}
+	if cfg.Organization != "" && !flags.Changed("organization") {
+		organization = cfg.Organization
+	}
+	if cfg.URL != "" && !flags.Changed("url") {
+		projectURL = cfg.URL
+	}
+	if cfg.Checksum != nil && !flags.Changed("checksum") {
+		checksum = *cfg.Checksum
+	}
+
+	configIgnorePatterns = cfg.Ignore
+	configCommentSyntaxes = cfg.CommentSyntaxes()
+	configPolicy = cfg.Policy
+	configHandlers = cfg.Handlers
+	configOverrides = cfg.PathOverrides()
+	for i, override := range configOverrides {
+		if override.Template != "" {
+			configOverrides[i].Template = resolveProjectPath(override.Template)
+		}
+	}
+}
+
+// resolveProjectPath interprets a relative path from the configuration file
+// relative to the project directory
+func resolveProjectPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(projectDir, path)
+}
+
+// Exit codes telling the outcomes of a run apart, 0 meaning that every file
+// is compliant or was changed as requested
+const (
+	// exitUsage is for invalid flags, settings or configuration
+	exitUsage = 1
+	// exitMissing is for files lacking the license header with check
+	exitMissing = 2
+	// exitConflicts is for files carrying a different license header
+	exitConflicts = 3
+	// exitFileErrors is for files that could not be read or written
+	exitFileErrors = 4
+)
+
+// exitWith ends the run with the exit code of its outcome, if it failed.
+// File errors take precedence over conflicts, and conflicts over missing
+// headers.
+func exitWith(result *licensed.Result) {
+	switch {
+	case len(result.Errors) > 0:
+		os.Exit(exitFileErrors)
+	case failOnConflict && len(result.Conflicts) > 0, checkOnly && len(result.Violations) > 0:
+		os.Exit(exitConflicts)
+	case checkOnly && (len(result.Missing) > 0 || len(result.Modified) > 0 || len(result.Problems) > 0):
+		os.Exit(exitMissing)
+	}
+}
+
+func printUsage() {
+	fmt.Println("Usage: licensed [command] [flags] [file...]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  init         set up .licensed.yaml and the LICENSE file interactively")
+	fmt.Println("  migrate      write .licensed.yaml from the configuration of --from addlicense, given")
+	fmt.Println("               its flags after --, or --from license-eye, given its .licenserc.yaml")
+	fmt.Println("  add          add license headers to files (default)")
+	fmt.Println("  check        report files missing the license header and exit non-zero")
+	fmt.Println("  remove       strip existing license headers from files")
+	fmt.Println("  update       extend the copyright years in existing headers to --year")
+	fmt.Println("  fix          repair headers left by earlier runs: --dedupe collapses stacked")
+	fmt.Println("               copies of the license header into one")
+	fmt.Println("  detect       report the license of each file's header and flag mismatches")
+	fmt.Println("  install-hook install a git pre-commit hook running check --staged")
+	fmt.Println("  list [query] list the supported licenses, optionally matching query")
+	fmt.Println("  completion   print the completion script of a shell: bash, zsh, fish or powershell")
+	fmt.Println("  undo         restore the files changed by the last run with --backup")
+	fmt.Println("  doctor       check the configuration, license, patterns and git setup")
+	fmt.Println("  watch        add the license header to new files as they are created, until interrupted")
+	fmt.Println("  deps         report the license of each dependency in go.mod, go.sum and node_modules")
+	fmt.Println("               and flag those breaking the allow and deny policy")
+	fmt.Println("  sbom         print an SPDX document of the project files with their licenses and")
+	fmt.Println("               copyright holders, in the --format spdx-json")
+	fmt.Println("  stats        report the share of files carrying the license header, by directory")
+	fmt.Println("               down to --depth and by language")
+	fmt.Println("  ci           print a pipeline of the --provider github, gitlab or azure running")
+	fmt.Println("               check on every push with the license and owner of the project config")
+	fmt.Println("  stamp        add the license header to the file read on stdin, selected by --lang,")
+	fmt.Println("               and write it to stdout")
+	fmt.Println("  version      print the version, commit and build date, and the number of bundled licenses")
+	fmt.Println()
+	fmt.Println("Every flag can also be set by an environment variable, e.g. LICENSED_LICENSE")
+	fmt.Println("for --license or LICENSED_OWNER_EMAIL for --owner-email, which takes")
+	fmt.Println("precedence over the config files but not over the command line. Repeatable")
+	fmt.Println("flags take one value per line.")
+	fmt.Println()
+	fmt.Println("Exit status is 1 for invalid flags or settings, 2 if files lack the license")
+	fmt.Println("header with check, 3 if files carry a different license header with")
+	fmt.Println("--on-conflict fail or detect, file headers break the policy of the")
+	fmt.Println("configuration with check, or dependencies break the license policy with")
+	fmt.Println("deps, and 4 if files could not be read or written.")
+	fmt.Println()
+	fmt.Println("Flags:")
+	pflag.PrintDefaults()
+}
+
+func fetchLicenses() {
+	// List all supported licenses, or those matching the query
+	licenses, err := licensed.Licenses(licenseDir)
+	if err != nil {
+		fail("cannot list licenses", "error", err)
+	}
+	if query := strings.Join(pflag.Args(), " "); query != "" {
+		licenses = licensed.SearchLicenses(licenses, query)
+	}
+
+	if outputFormat == "json" || outputFormat == "sarif" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if licenses == nil {
+			licenses = []licensed.LicenseInfo{}
+		}
+		if err := encoder.Encode(licenses); err != nil {
+			fail("cannot list licenses", "error", err)
+		}
+		os.Exit(0)
+	}
+
+	fmt.Println("Supported licenses:")
+	for _, license := range licenses {
+		fmt.Printf("- %-12s %-14s %s\n", license.Name, license.SPDXID, license.Title)
+		if license.Description != "" {
+			fmt.Printf("  %-12s %-14s %s\n", "", "", license.Description)
+		}
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/arzkar/licensed/pkg/licensed"
+)
+
+// migrateTemplateFile holds a custom header converted from another tool
+const migrateTemplateFile = ".licensed-header.tmpl"
+
+// migrateConfig converts the configuration of the tool selected by --from
+// into .licensed.yaml: the addlicense command line given after --, or the
+// license-eye configuration file given as argument, .licenserc.yaml by
+// default
+func migrateConfig() {
+	if !slices.Contains(licensed.MigrationSources, migrateFrom) {
+		fatal("unknown tool to migrate from", "from", migrateFrom, "supported", strings.Join(licensed.MigrationSources, ", "))
+	}
+
+	// Never overwrite an existing configuration by accident
+	for _, name := range licensed.ConfigFileNames {
+		path := filepath.Join(projectDir, name)
+		if _, err := os.Stat(path); err == nil && !forceHook {
+			fatal("config file already exists, use --force to replace it", "path", path)
+		}
+	}
+
+	var migration *licensed.Migration
+	var err error
+	switch migrateFrom {
+	case "addlicense":
+		migration, err = licensed.MigrateAddlicense(pflag.Args())
+	case "license-eye":
+		path := filepath.Join(projectDir, ".licenserc.yaml")
+		if pflag.NArg() > 0 {
+			path = pflag.Arg(0)
+		}
+		data, readErr := os.ReadFile(path)
+		if readErr != nil {
+			fail("cannot read license-eye configuration", "error", readErr)
+		}
+		migration, err = licensed.MigrateLicenseEye(data)
+	}
+	if err != nil {
+		fatal("cannot convert configuration", "from", migrateFrom, "error", err)
+	}
+
+	cfg := migration.Config
+	if migration.Template != "" {
+		path := filepath.Join(projectDir, migrateTemplateFile)
+		if err := licensed.AtomicWriteFile(path, []byte(migration.Template), 0644); err != nil {
+			fail("cannot write template", "path", path, "error", err)
+		}
+		logger.Info("wrote file", "path", path)
+		cfg.Template = migrateTemplateFile
+		cfg.Ignore = append(cfg.Ignore, migrateTemplateFile)
+	}
+	path, err := licensed.WriteConfig(projectDir, cfg)
+	if err != nil {
+		fail("cannot write config file", "path", path, "error", err)
+	}
+	logger.Info("wrote file", "path", path)
+	for _, note := range migration.Notes {
+		logger.Warn("not converted", "from", migrateFrom, "note", note)
+	}
+}
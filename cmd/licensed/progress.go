@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// progressInterval is the least time between two updates of the progress
+// line, which would otherwise flicker on fast runs
+const progressInterval = 100 * time.Millisecond
+
+// progress is the counter of files processed shown on stderr
+var progress = &progressLine{w: os.Stderr}
+
+// progressLine is a line on a terminal rewritten in place with the number
+// of files processed, cleared before anything else is written
+type progressLine struct {
+	mu      sync.Mutex
+	w       io.Writer
+	shown   bool
+	updated time.Time
+}
+
+// update shows done of total files processed, at most every
+// progressInterval, and clears the line once all are done
+func (l *progressLine) update(done, total int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if done >= total {
+		l.clearLocked()
+		return
+	}
+	if time.Since(l.updated) < progressInterval {
+		return
+	}
+	l.updated = time.Now()
+	fmt.Fprintf(l.w, "\r\x1b[Kprocessing %s/%s files", formatCount(done), formatCount(total))
+	l.shown = true
+}
+
+// clear erases the progress line if shown
+func (l *progressLine) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clearLocked()
+}
+
+func (l *progressLine) clearLocked() {
+	if l.shown {
+		fmt.Fprint(l.w, "\r\x1b[K")
+		l.shown = false
+	}
+}
+
+// progressWriter clears the progress line before writing to w, so that log
+// messages, diffs and prompts start on a clean line
+type progressWriter struct {
+	w io.Writer
+}
+
+func (pw progressWriter) Write(p []byte) (int, error) {
+	progress.clear()
+	return pw.w.Write(p)
+}
+
+// progressFunc returns the progress callback of the processor: nil with
+// --no-progress, --quiet, JSON logs or when stderr is not a terminal
+func progressFunc() func(done, total int) {
+	if noProgress || quiet || logFormat == "json" || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return progress.update
+}
+
+// formatCount formats n with commas between groups of thousands
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
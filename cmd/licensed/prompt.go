@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// isTerminal reports whether f is connected to a terminal
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// replaceAll and skipAll remember an "all" or "quit" answer for the rest
+// of the run
+var replaceAll, skipAll bool
+
+// confirmReplace decides whether a file with a different license header
+// should get the new header. The user is only prompted when stdin is a
+// terminal and neither --yes, --no-prompt nor --fail-on-conflict is set,
+// and can answer for all remaining files at once or look at the diff first.
+func confirmReplace(filePath, diff string) (bool, error) {
+	switch {
+	case assumeYes || replaceAll:
+		return true, nil
+	case skipAll:
+		return false, nil
+	case noPrompt || failOnConflict || !isTerminal(os.Stdin):
+		return false, nil
+	}
+
+	for {
+		progress.clear()
+		fmt.Fprintf(messages, "A different license header is detected in %s. Add the new header? [y]es / [n]o / [a]ll / [q]uit / [d]iff: ", filePath)
+		var input string
+		fmt.Scanln(&input)
+		switch strings.ToLower(strings.TrimSpace(input)) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		case "a", "all":
+			// Add the header to this and every following file
+			replaceAll = true
+			return true, nil
+		case "q", "quit":
+			// Leave this and every following file with a different header
+			skipAll = true
+			return false, nil
+		case "d", "diff":
+			fmt.Fprint(diffOutput(), diff)
+		}
+	}
+}
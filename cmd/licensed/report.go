@@ -0,0 +1,384 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/arzkar/licensed/pkg/licensed"
+)
+
+// reportFile is a file listed in a JSON report
+type reportFile struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// reportDetection is a license detected by the detect command
+type reportDetection struct {
+	Path       string   `json:"path"`
+	License    string   `json:"license"`
+	Similarity float64  `json:"similarity"`
+	Mismatch   bool     `json:"mismatch"`
+	Copyright  []string `json:"copyright,omitempty"`
+}
+
+// jsonReport is the --output json report of a run
+type jsonReport struct {
+	Command   string       `json:"command"`
+	DryRun    bool         `json:"dry_run"`
+	Changed   []reportFile `json:"changed"`
+	Licensed  []reportFile `json:"licensed"`
+	Generated []reportFile `json:"generated"`
+	Missing   []reportFile `json:"missing"`
+	Conflicts []reportFile `json:"conflicts"`
+	ReadOnly  []reportFile `json:"read_only"`
+	Modified  []reportFile `json:"modified"`
+	Skipped   []reportFile `json:"skipped"`
+	Problems  []reportFile `json:"problems"`
+	Errors    []reportFile `json:"errors"`
+
+	Violations []reportFile `json:"violations,omitempty"`
+
+	Detections []reportDetection `json:"detections,omitempty"`
+}
+
+// sarifLog is the subset of the SARIF 2.1.0 format written by --output sarif
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// writeReport prints the result of the run in the --output format. It does
+// nothing for the text format, whose messages are logged as the run goes.
+// The github format prints workflow commands instead, which GitHub Actions
+// turns into annotations.
+func writeReport(result *licensed.Result) {
+	var report any
+	switch outputFormat {
+	case "json":
+		report = newJSONReport(result)
+	case "sarif":
+		report = newSarifLog(result)
+	case "github":
+		writeAnnotations(result)
+		return
+	default:
+		return
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fail("cannot write report", "error", err)
+	}
+}
+
+func newJSONReport(result *licensed.Result) jsonReport {
+	report := jsonReport{
+		Command:   command,
+		DryRun:    dryRun,
+		Changed:   []reportFile{},
+		Licensed:  []reportFile{},
+		Generated: []reportFile{},
+		Missing:   []reportFile{},
+		Conflicts: []reportFile{},
+		ReadOnly:  []reportFile{},
+		Modified:  []reportFile{},
+		Skipped:   []reportFile{},
+		Problems:  []reportFile{},
+		Errors:    []reportFile{},
+	}
+	for _, filePath := range result.Changed {
+		report.Changed = append(report.Changed, reportFile{Path: filePath})
+	}
+	for _, filePath := range result.Licensed {
+		report.Licensed = append(report.Licensed, reportFile{Path: filePath})
+	}
+	for _, filePath := range result.Generated {
+		report.Generated = append(report.Generated, reportFile{Path: filePath})
+	}
+	for _, filePath := range result.Missing {
+		report.Missing = append(report.Missing, reportFile{Path: filePath, Line: result.Lines[filePath]})
+	}
+	for _, filePath := range result.Conflicts {
+		report.Conflicts = append(report.Conflicts, reportFile{Path: filePath, Line: result.Lines[filePath]})
+	}
+	for _, filePath := range result.ReadOnly {
+		report.ReadOnly = append(report.ReadOnly, reportFile{Path: filePath})
+	}
+	for _, filePath := range result.Modified {
+		report.Modified = append(report.Modified, reportFile{Path: filePath, Line: result.Lines[filePath]})
+	}
+	for _, reason := range skippedReasons(result) {
+		for _, filePath := range result.Skipped[reason] {
+			report.Skipped = append(report.Skipped, reportFile{Path: filePath, Reason: reason})
+		}
+	}
+	for _, problem := range result.Problems {
+		report.Problems = append(report.Problems, reportFile{Path: problem.Path, Reason: problem.Message})
+	}
+	for _, problem := range result.Errors {
+		report.Errors = append(report.Errors, reportFile{Path: problem.Path, Reason: problem.Message})
+	}
+	for _, violation := range result.Violations {
+		report.Violations = append(report.Violations, reportFile{Path: violation.Path, Reason: violation.Message})
+	}
+	for _, detection := range result.Detections {
+		report.Detections = append(report.Detections, reportDetection(detection))
+	}
+	return report
+}
+
+func newSarifLog(result *licensed.Result) sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "licensed",
+			InformationURI: "https://github.com/arzkar/licensed",
+			Rules: []sarifRule{
+				{ID: "missing-license-header", ShortDescription: sarifMessage{Text: "File is missing the license header"}},
+				{ID: "conflicting-license-header", ShortDescription: sarifMessage{Text: "File has a different license header"}},
+				{ID: "modified-license-header", ShortDescription: sarifMessage{Text: "File header does not match its checksum marker"}},
+				{ID: "reuse-compliance", ShortDescription: sarifMessage{Text: "Project is not REUSE compliant"}},
+				{ID: "license-mismatch", ShortDescription: sarifMessage{Text: "File header names another license than configured"}},
+				{ID: "license-policy", ShortDescription: sarifMessage{Text: "File header breaks the license policy"}},
+				{ID: "read-only-file", ShortDescription: sarifMessage{Text: "File is read-only and was left untouched"}},
+				{ID: "processing-error", ShortDescription: sarifMessage{Text: "File could not be processed"}},
+			},
+		}},
+		Results: []sarifResult{},
+	}
+
+	add := func(ruleID, level, message, filePath string) {
+		line := result.Lines[filePath]
+		if line == 0 {
+			line = 1
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  ruleID,
+			Level:   level,
+			Message: sarifMessage{Text: message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(filePath)},
+				Region:           sarifRegion{StartLine: line},
+			}}},
+		})
+	}
+	for _, filePath := range result.Missing {
+		add("missing-license-header", "error", "The license header is missing.", filePath)
+	}
+	for _, filePath := range result.Conflicts {
+		add("conflicting-license-header", "warning", "A different license header is detected.", filePath)
+	}
+	for _, filePath := range result.Modified {
+		add("modified-license-header", "error", "The license header was modified after it was added.", filePath)
+	}
+	for _, problem := range result.Problems {
+		add("reuse-compliance", "error", problem.Message+".", problem.Path)
+	}
+	for _, violation := range result.Violations {
+		add("license-policy", "error", violation.Message+".", violation.Path)
+	}
+	for _, filePath := range result.ReadOnly {
+		add("read-only-file", "warning", "The file is read-only and was left untouched.", filePath)
+	}
+	for _, problem := range result.Errors {
+		add("processing-error", "error", problem.Message+".", problem.Path)
+	}
+	for _, detection := range result.Detections {
+		if detection.Mismatch {
+			add("license-mismatch", "error", "The header names "+detection.License+".", detection.Path)
+		}
+	}
+
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
+
+// writeAnnotations prints a GitHub Actions workflow command for every file
+// with a problem, annotating it on the pull request
+func writeAnnotations(result *licensed.Result) {
+	header := "license header"
+	if licenseName != "" {
+		header = licensed.SPDXID(licenseName) + " header"
+	}
+	annotate := func(level, title, message, filePath string) {
+		line := result.Lines[filePath]
+		if line == 0 {
+			line = 1
+		}
+		fmt.Printf("::%s file=%s,line=%d,title=%s::%s\n", level, escapeProperty(filepath.ToSlash(filePath)), line, escapeProperty(title), escapeData(message))
+	}
+	for _, filePath := range result.Missing {
+		annotate("error", "Missing license header", "Missing "+header, filePath)
+	}
+	for _, filePath := range result.Conflicts {
+		annotate("warning", "Conflicting license header", "A different license header than the "+header+" is detected", filePath)
+	}
+	for _, filePath := range result.Modified {
+		annotate("error", "Modified license header", "The "+header+" was modified after it was added", filePath)
+	}
+	for _, problem := range result.Problems {
+		annotate("error", "REUSE compliance", problem.Message, problem.Path)
+	}
+	for _, violation := range result.Violations {
+		annotate("error", "License policy", violation.Message, violation.Path)
+	}
+	for _, filePath := range result.ReadOnly {
+		annotate("warning", "Read-only file", "The file is read-only and was left untouched, see --force-writable", filePath)
+	}
+	for _, problem := range result.Errors {
+		annotate("error", "Processing error", problem.Message, problem.Path)
+	}
+	for _, detection := range result.Detections {
+		if detection.Mismatch {
+			annotate("error", "License mismatch", "The header names "+detection.License+" instead of "+licensed.SPDXID(licenseName), detection.Path)
+		}
+	}
+}
+
+// escapeData escapes the message of a workflow command
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// skippedReasons returns the reasons files were skipped for, sorted
+func skippedReasons(result *licensed.Result) []string {
+	var reasons []string
+	for reason := range result.Skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	return reasons
+}
+
+// printSummary prints the counts of the run as a table, the skipped files
+// broken down by reason, unless stdout carries a JSON or SARIF report
+func printSummary(result *licensed.Result) {
+	if outputFormat != "text" && outputFormat != "github" {
+		return
+	}
+
+	var rows [][2]string
+	row := func(label string, n int) {
+		rows = append(rows, [2]string{label, strconv.Itoa(n)})
+	}
+	// Label the changed files by what the command did to them
+	changed, wouldChange := "Added", "Would add"
+	switch command {
+	case "remove":
+		changed, wouldChange = "Removed", "Would remove"
+	case "update":
+		changed, wouldChange = "Updated", "Would update"
+	case "fix":
+		changed, wouldChange = "Fixed", "Would fix"
+	}
+	if dryRun {
+		changed = wouldChange
+	}
+	switch {
+	case checkOnly:
+		row("Missing header", len(result.Missing))
+		if strict {
+			row("Modified header", len(result.Modified))
+		}
+		row("Already licensed", len(result.Licensed))
+		if len(configPolicy) > 0 {
+			row("Policy violations", len(result.Violations))
+		}
+	case command == "remove" || command == "update" || command == "fix":
+		row(changed, len(result.Changed))
+	default:
+		row(changed, len(result.Changed))
+		row("Already licensed", len(result.Licensed))
+	}
+	if len(result.Generated) > 0 {
+		row("Support files", len(result.Generated))
+	}
+
+	var skipped int
+	var reasons []string
+	for _, reason := range skippedReasons(result) {
+		skipped += len(result.Skipped[reason])
+		reasons = append(reasons, fmt.Sprintf("%s %d", reason, len(result.Skipped[reason])))
+	}
+	rows = append(rows, [2]string{"Skipped", strconv.Itoa(skipped)})
+	if len(reasons) > 0 {
+		rows[len(rows)-1][1] += " (" + strings.Join(reasons, ", ") + ")"
+	}
+	row("Conflicts", len(result.Conflicts))
+	if len(result.ReadOnly) > 0 {
+		row("Read-only", len(result.ReadOnly))
+	}
+	row("Errors", len(result.Errors))
+
+	fmt.Println("Summary:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range rows {
+		fmt.Fprintf(w, "  %s\t%s\n", r[0], r[1])
+	}
+	w.Flush()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/arzkar/licensed/pkg/licensed"
+)
+
+// expandDirs expands the glob patterns among the project directories dirs to
+// the directories they match, in lexical order
+func expandDirs(dirs []string) ([]string, error) {
+	var expanded []string
+	for _, dir := range dirs {
+		matches, err := filepath.Glob(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+		if len(matches) == 0 {
+			// A plain path is kept for the walk to report if it is missing
+			if !hasGlobMeta(dir) {
+				expanded = append(expanded, dir)
+				continue
+			}
+			return nil, fmt.Errorf("%s matches no directory", dir)
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				expanded = append(expanded, match)
+			}
+		}
+	}
+	if len(expanded) == 0 {
+		return nil, fmt.Errorf("%v match no directory", dirs)
+	}
+	return expanded, nil
+}
+
+// hasGlobMeta reports whether path holds characters of a glob pattern
+func hasGlobMeta(path string) bool {
+	for _, c := range path {
+		switch c {
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+// forEachRoot runs run with a processor of every project directory in turn
+// and returns their results combined
+func forEachRoot(run func(*licensed.Processor) (*licensed.Result, error)) *licensed.Result {
+	combined := &licensed.Result{}
+	for _, dir := range projectDirs {
+		projectDir = dir
+		if multiRoot {
+			logger.Debug("processing project directory", "dir", dir)
+		}
+		result, err := run(newProcessor())
+		if err != nil {
+			fail("cannot traverse directory", "dir", dir, "error", err)
+		}
+		combined.Merge(result)
+	}
+	return combined
+}
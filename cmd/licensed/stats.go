@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/arzkar/licensed/pkg/licensed"
+)
+
+// reportCoverage is the header coverage of a directory or language in the
+// stats command's JSON output
+type reportCoverage struct {
+	Name     string  `json:"name,omitempty"`
+	Files    int     `json:"files"`
+	Licensed int     `json:"licensed"`
+	Missing  int     `json:"missing"`
+	Percent  float64 `json:"percent"`
+}
+
+// reportStats is the JSON output of the stats command
+type reportStats struct {
+	Total       reportCoverage   `json:"total"`
+	Directories []reportCoverage `json:"directories"`
+	Languages   []reportCoverage `json:"languages"`
+}
+
+func newReportCoverage(name string, coverage licensed.Coverage) reportCoverage {
+	return reportCoverage{
+		Name:     name,
+		Files:    coverage.Files,
+		Licensed: coverage.Licensed,
+		Missing:  coverage.Files - coverage.Licensed,
+		Percent:  math.Round(coverage.Percent()*10) / 10,
+	}
+}
+
+// sortedCoverages returns coverages sorted by name
+func sortedCoverages(coverages map[string]*licensed.Coverage) []reportCoverage {
+	report := []reportCoverage{}
+	for name, coverage := range coverages {
+		report = append(report, newReportCoverage(name, *coverage))
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Name < report[j].Name })
+	return report
+}
+
+// printStats reports the share of the eligible files carrying the license
+// header, in total and by directory and language
+func printStats() {
+	if !headerConfigured() || len(owners) == 0 {
+		printUsage()
+		os.Exit(exitUsage)
+	}
+	stats, err := newProcessor().Stats(statsDepth)
+	if err != nil {
+		fail("cannot traverse directory", "error", err)
+	}
+	report := reportStats{
+		Total:       newReportCoverage("", stats.Total),
+		Directories: sortedCoverages(stats.Directories),
+		Languages:   sortedCoverages(stats.Languages),
+	}
+
+	if outputFormat == "json" || outputFormat == "sarif" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fail("cannot write report", "error", err)
+		}
+	} else {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		printCoverages(writer, "Directory", report.Directories)
+		fmt.Fprintln(writer)
+		printCoverages(writer, "Language", report.Languages)
+		writer.Flush()
+		fmt.Println()
+		fmt.Printf("%s of %s files carry the license header (%.1f%%)\n", formatCount(report.Total.Licensed), formatCount(report.Total.Files), report.Total.Percent)
+	}
+
+	if len(stats.Result.Errors) > 0 {
+		os.Exit(exitFileErrors)
+	}
+}
+
+// printCoverages writes a table of coverages under the heading of their
+// names
+func printCoverages(writer *tabwriter.Writer, heading string, coverages []reportCoverage) {
+	fmt.Fprintf(writer, "%s\tFiles\tLicensed\tCoverage\n", heading)
+	for _, coverage := range coverages {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%.1f%%\n", coverage.Name, formatCount(coverage.Files), formatCount(coverage.Licensed), coverage.Percent)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/arzkar/licensed/pkg/licensed"
+)
+
+// Build metadata injected at build time, e.g.
+//
+//	go build -ldflags "-X main.version=1.4.0 -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%FT%TZ)" ./cmd/licensed
+//
+// Builds without them fall back to the module version and the VCS
+// information recorded by the go command
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// reportVersion is the output of the version command
+type reportVersion struct {
+	Version  string `json:"version"`
+	Commit   string `json:"commit,omitempty"`
+	Modified bool   `json:"modified,omitempty"`
+	Date     string `json:"date,omitempty"`
+	Go       string `json:"go"`
+	Platform string `json:"platform"`
+	Licenses int    `json:"licenses"`
+}
+
+// buildVersion returns the version, commit and build date of the binary
+func buildVersion() reportVersion {
+	report := reportVersion{
+		Version:  version,
+		Commit:   commit,
+		Date:     date,
+		Go:       runtime.Version(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if report.Version == "" && info.Main.Version != "(devel)" {
+			report.Version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if report.Commit == "" {
+					report.Commit = setting.Value
+				}
+			case "vcs.time":
+				if report.Date == "" {
+					report.Date = setting.Value
+				}
+			case "vcs.modified":
+				report.Modified = commit == "" && setting.Value == "true"
+			}
+		}
+	}
+	if report.Version == "" {
+		report.Version = "dev"
+	}
+	return report
+}
+
+// printVersion prints the version, commit and build date of the binary and
+// the number of bundled licenses, for bug reports and caching in CI
+func printVersion() {
+	report := buildVersion()
+	if names, err := licensed.AvailableLicenses(""); err == nil {
+		report.Licenses = len(names)
+	}
+
+	if outputFormat == "json" || outputFormat == "sarif" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fail("cannot write version", "error", err)
+		}
+		return
+	}
+
+	fmt.Println("licensed", report.Version)
+	if report.Commit != "" {
+		if report.Modified {
+			report.Commit += " (modified)"
+		}
+		fmt.Println("commit:  ", report.Commit)
+	}
+	if report.Date != "" {
+		fmt.Println("built:   ", report.Date)
+	}
+	fmt.Println("go:      ", report.Go, report.Platform)
+	fmt.Println("licenses:", report.Licenses, "bundled")
+}
@@ -1,5 +1,13 @@
-module license
+module github.com/arzkar/licensed
 
 go 1.22.0
 
-require github.com/spf13/pflag v1.0.5
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/spf13/pflag v1.0.5
+	golang.org/x/term v0.20.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.20.0 // indirect
@@ -0,0 +1,135 @@
+package licensed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// BackupDir holds the originals of the files changed by the last run with
+// Backup, relative to the project directory
+const BackupDir = ".licensed-backup"
+
+// backupManifest lists the files saved in BackupDir, in the order they were
+// changed
+type backupManifest struct {
+	Files []backupEntry `json:"files"`
+}
+
+// backupEntry is a file changed by the last run. Backup names its original
+// in BackupDir, or is empty if the run created the file.
+type backupEntry struct {
+	Path   string `json:"path"`
+	Backup string `json:"backup,omitempty"`
+}
+
+// backupFile saves the original of filePath before it is written. The first
+// backup of a run replaces those of the previous run.
+func (p *Processor) backupFile(filePath string) error {
+	dir := filepath.Join(p.opts.Dir, BackupDir)
+	if p.backups == nil {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		p.backups = &backupManifest{}
+	}
+
+	path, err := filepath.Abs(filePath)
+	if err != nil {
+		return err
+	}
+	for _, entry := range p.backups.Files {
+		if entry.Path == path {
+			return nil
+		}
+	}
+
+	// Copy the original, unless the run creates the file
+	entry := backupEntry{Path: path}
+	original, err := os.Open(path)
+	switch {
+	case err == nil:
+		defer original.Close()
+		entry.Backup = strconv.Itoa(len(p.backups.Files))
+		saved, err := os.Create(filepath.Join(dir, entry.Backup))
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(saved, original); err != nil {
+			saved.Close()
+			return err
+		}
+		if err := saved.Close(); err != nil {
+			return err
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	// Save the manifest right away so an interrupted run can be undone
+	p.backups.Files = append(p.backups.Files, entry)
+	data, err := json.MarshalIndent(p.backups, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "manifest.json"), data, 0644)
+}
+
+// Undo restores the files changed by the last run with Backup from
+// BackupDir, deleting the files it created, then removes BackupDir
+func (p *Processor) Undo() (*Result, error) {
+	unlock, err := p.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	dir := filepath.Join(p.opts.Dir, BackupDir)
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no backup found in %s", dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest backupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", dir, err)
+	}
+
+	result := &Result{}
+	for i := len(manifest.Files) - 1; i >= 0; i-- {
+		entry := manifest.Files[i]
+		if entry.Backup == "" {
+			if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+				p.fileError(result, entry.Path, err)
+				continue
+			}
+			// Drop the directory created for the file, such as LICENSES/, if now empty
+			os.Remove(filepath.Dir(entry.Path))
+		} else {
+			content, err := os.ReadFile(filepath.Join(dir, entry.Backup))
+			if err == nil {
+				err = AtomicWriteFile(entry.Path, content, 0644)
+			}
+			if err != nil {
+				p.fileError(result, entry.Path, err)
+				continue
+			}
+		}
+		result.Changed = append(result.Changed, entry.Path)
+	}
+
+	// Keep the backup around if some files could not be restored
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+	return result, os.RemoveAll(dir)
+}
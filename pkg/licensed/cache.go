@@ -0,0 +1,187 @@
+package licensed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultCacheFile is the cache file the CLI keeps in the project directory
+const DefaultCacheFile = ".licensed-cache"
+
+// Header statuses of the files in the cache
+const (
+	cacheLicensed = "licensed"
+	cacheMissing  = "missing"
+)
+
+// licenseCache maps file paths to the state they had when they were last
+// checked. Key identifies the license and variables the entries were
+// recorded for.
+type licenseCache struct {
+	Key   string                `json:"key"`
+	Files map[string]cacheEntry `json:"files"`
+
+	// loaded is the content of the cache file when it was loaded
+	loaded []byte
+}
+
+// cacheEntry is the state of a file when it was last checked: its size,
+// modification time and content hash, and whether it carried the header.
+// Line is where a missing header belongs.
+type cacheEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Hash    string `json:"hash"`
+	Status  string `json:"status"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// cacheKey derives a cache key from the values that affect the rendered header
+func cacheKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashFile returns the content hash of filePath, reading it in chunks
+func hashFile(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func loadCache(path, key string) *licenseCache {
+	cache := &licenseCache{Key: key, Files: make(map[string]cacheEntry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	cache.loaded = data
+
+	// Discard entries recorded for a different license or different variables
+	var stored licenseCache
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Key != key || stored.Files == nil {
+		return cache
+	}
+	cache.Files = stored.Files
+	return cache
+}
+
+// lookup returns the entry of filePath if the file is unchanged since it was
+// recorded. The content is only hashed if its size or modification time
+// differ, e.g. after a checkout.
+func (c *licenseCache) lookup(filePath string) (cacheEntry, bool) {
+	entry, ok := c.Files[filePath]
+	if !ok {
+		return entry, false
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return entry, false
+	}
+	if entry.ModTime != 0 && info.Size() == entry.Size && info.ModTime().UnixNano() == entry.ModTime {
+		return entry, true
+	}
+	hash, err := hashFile(filePath)
+	if err != nil || hash != entry.Hash {
+		return entry, false
+	}
+	entry.Size, entry.ModTime = info.Size(), stableModTime(info)
+	c.Files[filePath] = entry
+	return entry, true
+}
+
+// record stores the state of filePath after it was checked for header with
+// hasHeader, or forgets the file if it cannot be read
+func (c *licenseCache) record(filePath, header string, hasHeader func(content, header, filePath string) bool) {
+	head, err := readHead(filePath, headSize(header))
+	if err != nil {
+		delete(c.Files, filePath)
+		return
+	}
+	if hasHeader(head, header, filePath) {
+		c.store(filePath, cacheEntry{Status: cacheLicensed})
+	} else {
+		delete(c.Files, filePath)
+	}
+}
+
+// store records entry for filePath along with its current state
+func (c *licenseCache) store(filePath string, entry cacheEntry) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		delete(c.Files, filePath)
+		return
+	}
+	hash, err := hashFile(filePath)
+	if err != nil {
+		delete(c.Files, filePath)
+		return
+	}
+	entry.Size, entry.ModTime, entry.Hash = info.Size(), stableModTime(info), hash
+	c.Files[filePath] = entry
+}
+
+// stableModTime returns the modification time of a file in nanoseconds, or
+// 0 if it is so recent that the file may still change within the same
+// timestamp, which forces hashing it next time
+func stableModTime(info os.FileInfo) int64 {
+	if time.Since(info.ModTime()) < 2*time.Second {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// save writes the cache to path, dropping the entries of deleted files. The
+// file is left alone if no entry changed, so that a run changing nothing
+// leaves the working tree as it is.
+func (c *licenseCache) save(path string) error {
+	for filePath := range c.Files {
+		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+			delete(c.Files, filePath)
+		}
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, c.loaded) {
+		return nil
+	}
+	if err := AtomicWriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	c.loaded = data
+	return nil
+}
+
+// isCacheFile reports whether filePath is the cache file itself
+func (p *Processor) isCacheFile(filePath string) bool {
+	if p.opts.CacheFile == "" {
+		return false
+	}
+	a, err := filepath.Abs(filePath)
+	if err != nil {
+		return false
+	}
+	b, err := filepath.Abs(p.opts.CacheFile)
+	if err != nil {
+		return false
+	}
+	return a == b
+}
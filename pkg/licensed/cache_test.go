@@ -0,0 +1,50 @@
+package licensed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheSaveUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(filePath, []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, DefaultCacheFile)
+
+	cache := loadCache(path, "key")
+	cache.store(filePath, cacheEntry{Status: cacheLicensed})
+	if err := cache.save(path); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	// Storing the same state again changes no entry
+	cache = loadCache(path, "key")
+	cache.store(filePath, cacheEntry{Status: cacheLicensed})
+	if err := cache.save(path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("unchanged cache rewritten at %v", info.ModTime())
+	}
+
+	// A changed entry is written
+	cache.store(filePath, cacheEntry{Status: cacheMissing})
+	if err := cache.save(path); err != nil {
+		t.Fatal(err)
+	}
+	if cache = loadCache(path, "key"); cache.Files[filePath].Status != cacheMissing {
+		t.Errorf("changed entry not written: %+v", cache.Files[filePath])
+	}
+}
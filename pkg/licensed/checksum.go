@@ -0,0 +1,71 @@
+package licensed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// checksumMarker matches the marker ending a header written with Checksum
+var checksumMarker = regexp.MustCompile(`licensed: sha256:([0-9a-f]{16})`)
+
+// headerChecksum returns the checksum of header text, which only depends on
+// its words and punctuation, not on whitespace or wrapping
+func headerChecksum(text string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(text), " ")))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// withChecksum appends the checksum marker of licenseContent to it
+func withChecksum(licenseContent string) string {
+	text := strings.TrimRight(licenseContent, "\n")
+	return text + "\nlicensed: sha256:" + headerChecksum(text)
+}
+
+// verifyChecksum reports whether the header at the top of content, after
+// any preamble lines and optionally a file-level docstring, ends with a
+// checksum marker matching its text. Otherwise the line the header starts on
+// is returned too, or 0 if there is no header at all.
+func verifyChecksum(content, filePath string, syntax CommentSyntax) (bool, int) {
+	_, text := splitBOM(content)
+	preamble, rest := splitPreamble(text, filePath)
+	line := strings.Count(preamble, "\n") + 1
+
+	block := rest[:leadingCommentLength(rest, syntax)]
+	if !checksumMarker.MatchString(block) && !looksLikeLicense(block) {
+		doc, afterDoc := splitDocstring(rest, filePath)
+		line += strings.Count(doc, "\n")
+		block = afterDoc[:leadingCommentLength(afterDoc, syntax)]
+	}
+	if !checksumMarker.MatchString(block) && !looksLikeLicense(block) {
+		return false, 0
+	}
+
+	match := checksumMarker.FindStringSubmatchIndex(block)
+	if match == nil {
+		return false, line
+	}
+	header := uncomment(block[:match[0]], syntax)
+	return headerChecksum(header) == block[match[2]:match[3]], line
+}
+
+// uncomment strips the comment markers of syntax from each line of a
+// comment block
+func uncomment(block string, syntax CommentSyntax) string {
+	lines := strings.Split(block, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if syntax.BlockOpen != "" {
+			line = strings.TrimSpace(strings.TrimPrefix(line, syntax.BlockOpen))
+			line = strings.TrimSpace(strings.TrimSuffix(line, syntax.BlockClose))
+		}
+		for _, prefix := range []string{syntax.LinePrefix, strings.TrimSpace(syntax.BlockDecoration)} {
+			if prefix != "" {
+				line = strings.TrimSpace(strings.TrimPrefix(line, prefix))
+			}
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
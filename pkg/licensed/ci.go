@@ -0,0 +1,119 @@
+package licensed
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CIProviders lists the CI systems CIPipeline writes a pipeline for
+var CIProviders = []string{"github", "gitlab", "azure"}
+
+// DefaultCIInstall is the command installing licensed in CIPipeline
+const DefaultCIInstall = "go install github.com/arzkar/licensed/cmd/licensed@latest"
+
+// CIPipeline returns a pipeline of provider that installs licensed with
+// install and runs command, e.g. licensed check, failing on a non-zero exit
+// status. The check cache is kept between runs, keyed by the hash of
+// keyFiles, the configuration and ignore files of the project, if any.
+func CIPipeline(provider, install, command string, keyFiles []string) (string, error) {
+	var b strings.Builder
+	switch provider {
+	case "github":
+		key := "licensed-"
+		if len(keyFiles) > 0 {
+			hashes := make([]string, len(keyFiles))
+			for i, file := range keyFiles {
+				hashes[i] = "'" + file + "'"
+			}
+			key += "${{ hashFiles(" + strings.Join(hashes, ", ") + ") }}-"
+		}
+		fmt.Fprintf(&b, `# .github/workflows/licensed.yml
+name: License headers
+on:
+  push:
+  pull_request:
+jobs:
+  licensed:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@v5
+        with:
+          go-version: stable
+      - name: Install licensed
+        run: %s
+      - uses: actions/cache@v4
+        with:
+          path: %s
+          key: %s${{ github.sha }}
+          restore-keys: %s
+      # Exits with 2 for missing headers, 3 for conflicts or policy
+      # violations and 4 for unreadable files, failing the job
+      - name: Check license headers
+        run: %s --output github
+`, install, DefaultCacheFile, key, key, command)
+	case "gitlab":
+		key := "    key: licensed\n"
+		if len(keyFiles) > 0 {
+			key = "    key:\n      prefix: licensed\n      files:\n" + yamlList(keyFiles, "        ")
+		}
+		fmt.Fprintf(&b, `# .gitlab-ci.yml
+licensed:
+  stage: test
+  image: golang:latest
+  cache:
+%s    paths:
+      - %s
+  script:
+    - %s
+    - export PATH="$PATH:$(go env GOPATH)/bin"
+    # Exits with 2 for missing headers, 3 for conflicts or policy
+    # violations and 4 for unreadable files, failing the job
+    - %s --output json > licensed-report.json
+  artifacts:
+    when: always
+    paths:
+      - licensed-report.json
+`, key, DefaultCacheFile, install, command)
+	case "azure":
+		key := `licensed | "$(Agent.OS)"`
+		if len(keyFiles) > 0 {
+			key += " | " + strings.Join(keyFiles, " | ")
+		}
+		fmt.Fprintf(&b, `# azure-pipelines.yml
+trigger:
+  - main
+pool:
+  vmImage: ubuntu-latest
+steps:
+  - task: GoTool@0
+    inputs:
+      version: '1.22'
+  - script: |
+      %s
+      echo "##vso[task.prependpath]$(go env GOPATH)/bin"
+    displayName: Install licensed
+  - task: Cache@2
+    inputs:
+      key: '%s'
+      restoreKeys: 'licensed | "$(Agent.OS)"'
+      path: %s
+  # Exits with 2 for missing headers, 3 for conflicts or policy violations
+  # and 4 for unreadable files, failing the job
+  - script: %s
+    displayName: Check license headers
+`, install, key, DefaultCacheFile, command)
+	default:
+		return "", fmt.Errorf("unknown CI provider %q, expected %s", provider, strings.Join(CIProviders, ", "))
+	}
+	return b.String(), nil
+}
+
+// yamlList writes items as a YAML block sequence indented by indent
+func yamlList(items []string, indent string) string {
+	var b strings.Builder
+	for _, item := range items {
+		b.WriteString(indent + "- " + item + "\n")
+	}
+	return b.String()
+}
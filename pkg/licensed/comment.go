@@ -0,0 +1,266 @@
+package licensed
+
+import (
+	_ "embed"
+	"regexp"
+	"strings"
+)
+
+// commentSyntaxFile is the default mapping of file extensions to comment syntax
+//
+//go:embed comment-syntax.txt
+var commentSyntaxFile []byte
+
+// CommentSyntax describes how comments are written in a language. Languages
+// with line comments set LinePrefix; languages that only support block
+// comments set BlockOpen and BlockClose instead, with an optional
+// BlockDecoration starting each line inside the block (e.g. " *").
+// Languages with both are stamped with line comments, the block form only
+// being recognized in existing headers.
+type CommentSyntax struct {
+	LinePrefix      string
+	BlockOpen       string
+	BlockClose      string
+	BlockDecoration string
+	// Width overrides the column headers are wrapped at if not zero, a
+	// negative width disables wrapping
+	Width int
+	// Banner is repeated up to the wrapping column to frame the header
+	// with banner lines, e.g. "*" for /***** and *****/
+	Banner string
+}
+
+// ParseCommentSyntax parses the comment-syntax.txt format. Each line is
+// either <ext>:<line_prefix> or <ext>:<line_prefix>:<block_open>:<block_close>
+// with an optional trailing :<block_decoration>, where the line prefix may be
+// empty for block-only languages.
+func ParseCommentSyntax(data []byte) map[string]CommentSyntax {
+	syntaxes := make(map[string]CommentSyntax)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ":")
+		if len(fields) != 2 && len(fields) != 4 && len(fields) != 5 {
+			continue
+		}
+
+		syntax := CommentSyntax{LinePrefix: fields[1]}
+		if len(fields) >= 4 {
+			syntax.BlockOpen = fields[2]
+			syntax.BlockClose = fields[3]
+		}
+		if len(fields) == 5 {
+			syntax.BlockDecoration = fields[4]
+		}
+		syntaxes[strings.ToLower(fields[0])] = syntax
+	}
+	return syntaxes
+}
+
+// commentPrefix returns the token that starts a comment in this syntax
+func (s CommentSyntax) commentPrefix() string {
+	if s.LinePrefix != "" {
+		return s.LinePrefix
+	}
+	return s.BlockOpen
+}
+
+// FormatHeader renders the license content as a comment block, wrapping
+// lines longer than width columns unless the syntax sets its own width
+func FormatHeader(licenseContent string, syntax CommentSyntax, width int) string {
+	if syntax.Width != 0 {
+		width = syntax.Width
+	}
+
+	// Choose how each line of the block starts
+	linePrefix := syntax.LinePrefix
+	if linePrefix == "" {
+		linePrefix = syntax.BlockDecoration
+	}
+
+	var lines []string
+	switch {
+	case syntax.LinePrefix == "":
+		lines = append(lines, syntax.bannerLine(syntax.BlockOpen, "", width))
+	case syntax.Banner != "":
+		lines = append(lines, syntax.bannerLine(syntax.LinePrefix, "", width))
+	}
+	wrapWidth := width - len(linePrefix) - 1
+	for _, line := range reflowParagraphs(strings.Split(strings.TrimRight(licenseContent, "\n"), "\n"), wrapWidth) {
+		for _, wrapped := range wrapLine(line, wrapWidth) {
+			switch {
+			case linePrefix == "":
+				lines = append(lines, wrapped)
+			case strings.TrimSpace(wrapped) == "":
+				lines = append(lines, strings.TrimRight(linePrefix, " \t"))
+			case strings.TrimSpace(linePrefix) == "":
+				// An indentation rather than a decoration
+				lines = append(lines, linePrefix+wrapped)
+			default:
+				lines = append(lines, linePrefix+" "+wrapped)
+			}
+		}
+	}
+	if syntax.LinePrefix == "" {
+		// Align the closing delimiter with the decoration
+		indent := syntax.BlockDecoration[:len(syntax.BlockDecoration)-len(strings.TrimLeft(syntax.BlockDecoration, " \t"))]
+		if strings.TrimSpace(syntax.BlockDecoration) == "" {
+			indent = ""
+		}
+		lines = append(lines, syntax.bannerLine(indent, syntax.BlockClose, width))
+	} else if syntax.Banner != "" {
+		lines = append(lines, syntax.bannerLine(syntax.LinePrefix, "", width))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatHeader is FormatHeader at the configured width, leaving the lines of
+// a HeaderFile header as they are. Otherwise the header of filePath is
+// encoded in latin1 if its .editorconfig charset says so, and with
+// IndentBlocks the lines inside blocks without decoration are
+// indented as it declares.
+func (p *Processor) formatHeader(filePath, licenseContent string, syntax CommentSyntax) string {
+	if p.opts.HeaderFile != "" {
+		syntax.Width = 0
+		return FormatHeader(licenseContent, syntax, 0)
+	}
+	if filePath == "" {
+		return FormatHeader(licenseContent, syntax, p.opts.Width)
+	}
+
+	config := p.editorconfigFor(filePath)
+	if p.opts.IndentBlocks && syntax.LinePrefix == "" && strings.TrimSpace(syntax.BlockDecoration) == "" {
+		if indent := config.indent(); indent != "" {
+			syntax.BlockDecoration = indent
+		}
+	}
+	header := FormatHeader(licenseContent, syntax, p.opts.Width)
+	if config.charset == "latin1" {
+		header = encodeLatin1(header)
+	}
+	return header
+}
+
+// bannerLine returns left and right with the Banner repeated between them
+// up to width columns, 80 if wrapping is disabled, or left+right without a
+// Banner
+func (s CommentSyntax) bannerLine(left, right string, width int) string {
+	if s.Banner == "" {
+		return left + right
+	}
+	if width <= 0 {
+		width = 80
+	}
+	n := (width - len(left) - len(right)) / len(s.Banner)
+	return left + strings.Repeat(s.Banner, max(n, 1)) + right
+}
+
+// hasDelimiters reports whether the syntax sets the comment delimiters,
+// rather than only the Width and Banner of an extension's default syntax
+func (s CommentSyntax) hasDelimiters() bool {
+	return s.LinePrefix != "" || s.BlockOpen != "" || s.BlockClose != "" || s.BlockDecoration != ""
+}
+
+// listItem matches the start of an item of a numbered, lettered or bulleted
+// list, e.g. "1. ", "a) " or "- "
+var listItem = regexp.MustCompile(`^[ \t]*(?:\d+[.)]|[a-z][.)]|\(\w+\)|[-*•])[ \t]`)
+
+// reflowParagraphs joins the lines of every paragraph, consecutive lines
+// that are not blank, with a line longer than width or a last word that was
+// wrapped onto a line of its own. Wrapping it then fills the lines evenly
+// instead of leaving a word or two of each line behind. List items and
+// lines indented deeper than the one before start a new line, and other
+// paragraphs are kept as they are.
+func reflowParagraphs(lines []string, width int) []string {
+	if width < 1 {
+		return lines
+	}
+
+	var reflowed []string
+	for start := 0; start < len(lines); {
+		end := start
+		for end < len(lines) && strings.TrimSpace(lines[end]) != "" {
+			end++
+		}
+		if end == start {
+			reflowed = append(reflowed, lines[start])
+			start++
+			continue
+		}
+
+		paragraph := lines[start:end]
+		start = end
+		overflows := false
+		for _, line := range paragraph {
+			if len(strings.TrimRight(line, " \t\r")) > width {
+				overflows = true
+			}
+		}
+		if n := len(paragraph); n > 1 {
+			last := strings.Fields(paragraph[n-1])
+			prev := strings.TrimRight(paragraph[n-2], " \t\r")
+			if len(last) == 1 && len(prev)+1+len(last[0]) > width {
+				overflows = true
+			}
+		}
+		if !overflows {
+			reflowed = append(reflowed, paragraph...)
+			continue
+		}
+
+		joined := []string{strings.TrimRight(paragraph[0], " \t\r")}
+		for _, line := range paragraph[1:] {
+			line = strings.TrimRight(line, " \t\r")
+			last := joined[len(joined)-1]
+			if indentWidth(line) > indentWidth(last) || listItem.MatchString(line) {
+				joined = append(joined, line)
+				continue
+			}
+			joined[len(joined)-1] = last + " " + strings.TrimSpace(line)
+		}
+		reflowed = append(reflowed, joined...)
+	}
+	return reflowed
+}
+
+// indentWidth returns the length of the leading whitespace of line
+func indentWidth(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// wrapLine splits line at word boundaries into lines of at most width
+// columns, repeating its indentation on continuation lines. Words longer
+// than width are kept whole; a width below one disables wrapping.
+func wrapLine(line string, width int) []string {
+	line = strings.TrimRight(line, " \t\r")
+	if width < 1 || len(line) <= width {
+		return []string{line}
+	}
+
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	var wrapped []string
+	current := indent
+	for _, word := range strings.Fields(line) {
+		if len(current) > len(indent) && len(current)+1+len(word) > width {
+			wrapped = append(wrapped, current)
+			current = indent
+		}
+		if len(current) > len(indent) {
+			current += " "
+		}
+		current += word
+	}
+
+	// Move a word down rather than leave the last one on a line of its own
+	if n := len(wrapped); n > 0 && !strings.Contains(strings.TrimSpace(current), " ") {
+		prev := wrapped[n-1]
+		if i := strings.LastIndexByte(prev, ' '); i > len(indent) && strings.Contains(strings.TrimSpace(prev[:i]), " ") && len(current)+1+len(prev)-i-1 <= width {
+			wrapped[n-1] = prev[:i]
+			current = indent + prev[i+1:] + " " + strings.TrimSpace(current)
+		}
+	}
+	return append(wrapped, current)
+}
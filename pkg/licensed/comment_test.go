@@ -0,0 +1,95 @@
+package licensed
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCommentSyntax(t *testing.T) {
+	syntaxes := ParseCommentSyntax([]byte("# comment\n.lua:--:--[[:]]\n.HS:--:{-:-}\n.lhs::{-:-}\n.bad:--:{-\n"))
+	want := map[string]CommentSyntax{
+		".lua": {LinePrefix: "--", BlockOpen: "--[[", BlockClose: "]]"},
+		".hs":  {LinePrefix: "--", BlockOpen: "{-", BlockClose: "-}"},
+		".lhs": {BlockOpen: "{-", BlockClose: "-}"},
+	}
+	if len(syntaxes) != len(want) {
+		t.Errorf("got %d syntaxes, want %d: %#v", len(syntaxes), len(want), syntaxes)
+	}
+	for ext, syntax := range want {
+		if syntaxes[ext] != syntax {
+			t.Errorf("%s: got %#v, want %#v", ext, syntaxes[ext], syntax)
+		}
+	}
+
+	// The bundled mapping comments Lua and Haskell the same way
+	bundled := ParseCommentSyntax(commentSyntaxFile)
+	for _, ext := range []string{".lua", ".hs"} {
+		if bundled[ext] != want[ext] {
+			t.Errorf("bundled %s: got %#v, want %#v", ext, bundled[ext], want[ext])
+		}
+	}
+}
+
+func TestFormatHeader(t *testing.T) {
+	const license = "Copyright (c) 2024 x\n\nSPDX-License-Identifier: MIT"
+	tests := []struct {
+		name   string
+		ext    string
+		syntax string
+		want   string
+	}{
+		{
+			name:   "lua",
+			ext:    ".lua",
+			syntax: ".lua:--:--[[:]]",
+			want:   "-- Copyright (c) 2024 x\n--\n-- SPDX-License-Identifier: MIT",
+		},
+		{
+			name:   "haskell",
+			ext:    ".hs",
+			syntax: ".hs:--:{-:-}",
+			want:   "-- Copyright (c) 2024 x\n--\n-- SPDX-License-Identifier: MIT",
+		},
+		{
+			name:   "haskell block",
+			ext:    ".hs",
+			syntax: ".hs::{-:-}",
+			want:   "{-\nCopyright (c) 2024 x\n\nSPDX-License-Identifier: MIT\n-}",
+		},
+		{
+			name:   "haskell decorated block",
+			ext:    ".hs",
+			syntax: ".hs::{-:-}: |",
+			want:   "{-\n | Copyright (c) 2024 x\n |\n | SPDX-License-Identifier: MIT\n -}",
+		},
+	}
+	for _, tt := range tests {
+		syntax, ok := ParseCommentSyntax([]byte(tt.syntax))[tt.ext]
+		if !ok {
+			t.Fatalf("%s: cannot parse %q", tt.name, tt.syntax)
+		}
+		if got := FormatHeader(license, syntax, 0); got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Wrapping the default header leaves no word on a line of its own
+	_, text, err := readLicenseFile("mit", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundled := ParseCommentSyntax(commentSyntaxFile)
+	for _, ext := range []string{".go", ".css", ".lua"} {
+		header := FormatHeader(string(text), bundled[ext], 80)
+		for _, line := range strings.Split(header, "\n") {
+			if len(line) > 80 {
+				t.Errorf("%s: line longer than 80 columns: %q", ext, line)
+			}
+			words := strings.Fields(line)
+			if len(words) == 2 && (words[0] == "//" || words[0] == "*" || words[0] == "--") {
+				t.Errorf("%s: single word on a line:\n%s", ext, header)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package licensed
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileNames are the project configuration files looked up in the
+// project directory, in order of preference
+var ConfigFileNames = []string{".licensed.yaml", ".licensed.yml", ".licensed.toml"}
+
+// Config is the project configuration read from .licensed.yaml or
+// .licensed.toml. Command line flags override its values.
+type Config struct {
+	License         string                         `yaml:"license,omitempty" toml:"license,omitempty"`
+	Owner           string                         `yaml:"owner,omitempty" toml:"owner,omitempty"`
+	Email           string                         `yaml:"email,omitempty" toml:"email,omitempty"`
+	Owners          []Owner                        `yaml:"owners,omitempty" toml:"owners,omitempty"`
+	Year            string                         `yaml:"year,omitempty" toml:"year,omitempty"`
+	Ignore          []string                       `yaml:"ignore,omitempty" toml:"ignore,omitempty"`
+	Comments        map[string]CommentSyntaxConfig `yaml:"comments,omitempty" toml:"comments,omitempty"`
+	Template        string                         `yaml:"template,omitempty" toml:"template,omitempty"`
+	LicenseTextFile string                         `yaml:"license_text_file,omitempty" toml:"license_text_file,omitempty"`
+	HeaderFile      string                         `yaml:"header_file,omitempty" toml:"header_file,omitempty"`
+	SPDX            *bool                          `yaml:"spdx,omitempty" toml:"spdx,omitempty"`
+	FullText        *bool                          `yaml:"full_text,omitempty" toml:"full_text,omitempty"`
+	TrackedOnly     *bool                          `yaml:"tracked_only,omitempty" toml:"tracked_only,omitempty"`
+	CopyrightOnly   *bool                          `yaml:"copyright_only,omitempty" toml:"copyright_only,omitempty"`
+	REUSE           *bool                          `yaml:"reuse,omitempty" toml:"reuse,omitempty"`
+	BlankLines      *int                           `yaml:"blank_lines,omitempty" toml:"blank_lines,omitempty"`
+	Width           *int                           `yaml:"width,omitempty" toml:"width,omitempty"`
+	Position        string                         `yaml:"position,omitempty" toml:"position,omitempty"`
+	Checksum        *bool                          `yaml:"checksum,omitempty" toml:"checksum,omitempty"`
+	Project         string                         `yaml:"project,omitempty" toml:"project,omitempty"`
+	Organization    string                         `yaml:"organization,omitempty" toml:"organization,omitempty"`
+	URL             string                         `yaml:"url,omitempty" toml:"url,omitempty"`
+	Notice          []string                       `yaml:"notice,omitempty" toml:"notice,omitempty"`
+	Paths           []PathConfig                   `yaml:"paths,omitempty" toml:"paths,omitempty"`
+	OnConflict      string                         `yaml:"on_conflict,omitempty" toml:"on_conflict,omitempty"`
+	PreserveNotice  *bool                          `yaml:"preserve_original_notice,omitempty" toml:"preserve_original_notice,omitempty"`
+	Dependencies    DependencyPolicy               `yaml:"dependencies,omitempty" toml:"dependencies,omitempty"`
+	Policy          []PolicyRule                   `yaml:"policy,omitempty" toml:"policy,omitempty"`
+	Handlers        []FileHandler                  `yaml:"handlers,omitempty" toml:"handlers,omitempty"`
+	// Dirs are the project roots processed in its place, relative to the
+	// directory of the configuration file, such as services/*
+	Dirs []string `yaml:"dirs,omitempty" toml:"dirs,omitempty"`
+}
+
+// PathConfig overrides the license settings for the files matching Path, a
+// gitignore-style pattern relative to the project directory
+type PathConfig struct {
+	Path     string  `yaml:"path,omitempty" toml:"path,omitempty"`
+	License  string  `yaml:"license,omitempty" toml:"license,omitempty"`
+	Owner    string  `yaml:"owner,omitempty" toml:"owner,omitempty"`
+	Owners   []Owner `yaml:"owners,omitempty" toml:"owners,omitempty"`
+	Template string  `yaml:"template,omitempty" toml:"template,omitempty"`
+}
+
+// CommentSyntaxConfig overrides the comment syntax of one file extension
+type CommentSyntaxConfig struct {
+	Line            string `yaml:"line,omitempty" toml:"line,omitempty"`
+	BlockOpen       string `yaml:"block_open,omitempty" toml:"block_open,omitempty"`
+	BlockClose      string `yaml:"block_close,omitempty" toml:"block_close,omitempty"`
+	BlockDecoration string `yaml:"block_decoration,omitempty" toml:"block_decoration,omitempty"`
+	// Width is the column headers are wrapped at, 0 to disable wrapping
+	Width *int `yaml:"width,omitempty" toml:"width,omitempty"`
+	// Banner is repeated up to the width on the opening and closing lines
+	Banner string `yaml:"banner,omitempty" toml:"banner,omitempty"`
+}
+
+// LoadConfig reads the first configuration file present in dir. It returns
+// an empty path and no error if the project has no configuration file.
+func LoadConfig(dir string) (Config, string, error) {
+	return loadConfigFile(dir, ConfigFileNames)
+}
+
+// UserConfigFileNames are the user configuration files looked up in
+// UserConfigDir, in order of preference
+var UserConfigFileNames = []string{"config.yaml", "config.yml", "config.toml"}
+
+// UserConfigDir returns the directory of the user configuration,
+// $XDG_CONFIG_HOME/licensed or ~/.config/licensed
+func UserConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "licensed")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "licensed")
+}
+
+// LoadUserConfig reads the user configuration holding the personal defaults
+// of every project, such as the owner and the preferred license. Relative
+// template and license text paths are resolved against its directory. It
+// returns an empty path and no error if there is no user configuration.
+func LoadUserConfig() (Config, string, error) {
+	dir := UserConfigDir()
+	if dir == "" {
+		return Config{}, "", nil
+	}
+	cfg, path, err := loadConfigFile(dir, UserConfigFileNames)
+	if err != nil || path == "" {
+		return cfg, path, err
+	}
+	if cfg.Template != "" && !filepath.IsAbs(cfg.Template) {
+		cfg.Template = filepath.Join(dir, cfg.Template)
+	}
+	if cfg.LicenseTextFile != "" && !filepath.IsAbs(cfg.LicenseTextFile) {
+		cfg.LicenseTextFile = filepath.Join(dir, cfg.LicenseTextFile)
+	}
+	if cfg.HeaderFile != "" && !filepath.IsAbs(cfg.HeaderFile) {
+		cfg.HeaderFile = filepath.Join(dir, cfg.HeaderFile)
+	}
+	return cfg, path, nil
+}
+
+// loadConfigFile reads the first of the named configuration files present
+// in dir
+func loadConfigFile(dir string, names []string) (Config, string, error) {
+	var cfg Config
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return cfg, path, err
+		}
+
+		if filepath.Ext(name) == ".toml" {
+			err = toml.Unmarshal(data, &cfg)
+		} else {
+			err = yaml.Unmarshal(data, &cfg)
+		}
+		if err != nil {
+			return cfg, path, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		return cfg, path, nil
+	}
+	return cfg, "", nil
+}
+
+// Merge returns the configuration with the values set in override taking
+// precedence, as the project configuration does over the user one. The
+// ignore patterns of both apply and the comment syntaxes are merged by
+// extension, the copyright holders of override replace all others.
+func (c Config) Merge(override Config) Config {
+	if override.Owner != "" || len(override.Owners) > 0 {
+		c.Owner, c.Email, c.Owners = "", "", nil
+	}
+	ignore := append(append([]string(nil), c.Ignore...), override.Ignore...)
+	comments := make(map[string]CommentSyntaxConfig)
+	for ext, syntax := range c.Comments {
+		comments[ext] = syntax
+	}
+	for ext, syntax := range override.Comments {
+		comments[ext] = syntax
+	}
+
+	mergeValue(reflect.ValueOf(&c).Elem(), reflect.ValueOf(override))
+	c.Ignore = ignore
+	if len(comments) > 0 {
+		c.Comments = comments
+	}
+	return c
+}
+
+// mergeValue sets the fields of dst to those of src that are not zero,
+// descending into nested structs
+func mergeValue(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			mergeValue(dst.Field(i), field)
+		case !field.IsZero():
+			dst.Field(i).Set(field)
+		}
+	}
+}
+
+// WriteConfig writes cfg to .licensed.yaml in dir, returning its path
+func WriteConfig(dir string, cfg Config) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, ConfigFileNames[0])
+	return path, AtomicWriteFile(path, buf.Bytes(), 0644)
+}
+
+// CommentSyntaxes returns the comment syntax overrides of the configuration
+func (c Config) CommentSyntaxes() map[string]CommentSyntax {
+	syntaxes := make(map[string]CommentSyntax)
+	for ext, syntax := range c.Comments {
+		commentSyntax := CommentSyntax{
+			LinePrefix:      syntax.Line,
+			BlockOpen:       syntax.BlockOpen,
+			BlockClose:      syntax.BlockClose,
+			BlockDecoration: syntax.BlockDecoration,
+			Banner:          syntax.Banner,
+		}
+		if syntax.Width != nil {
+			// As with --width, 0 disables wrapping
+			commentSyntax.Width = *syntax.Width
+			if commentSyntax.Width == 0 {
+				commentSyntax.Width = -1
+			}
+		}
+		syntaxes[ext] = commentSyntax
+	}
+	return syntaxes
+}
+
+// PathOverrides returns the per-path license settings of the configuration
+func (c Config) PathOverrides() []PathOverride {
+	var overrides []PathOverride
+	for _, path := range c.Paths {
+		override := PathOverride{
+			Pattern:  path.Path,
+			License:  path.License,
+			Owners:   path.Owners,
+			Template: path.Template,
+		}
+		if path.Owner != "" {
+			override.Owners = append([]Owner{{Name: path.Owner}}, override.Owners...)
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides
+}
@@ -0,0 +1,174 @@
+package licensed
+
+import (
+	"errors"
+	"strings"
+)
+
+// headerCopy is one of the license headers stacked at the top of a file,
+// with the blank lines separating it from what follows
+type headerCopy struct {
+	text      string
+	separator string
+}
+
+// Dedupe collapses the copies of a license header that earlier runs stacked
+// at the top of the files of the project into one. Of consecutive headers
+// that only differ in comment style, wrapping or years, the one matching the
+// header rendered for the file is kept, otherwise the first.
+func (p *Processor) Dedupe() (*Result, error) {
+	unlock, err := p.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	renderers := make(map[int]*headerRenderer)
+
+	result := &Result{}
+	err = p.forEachFile(result, func(filePath string) error {
+		commentSyntax, ok := p.commentSyntaxFor(filePath)
+		if !ok {
+			p.skipFile(result, "unknown type", filePath)
+			return nil
+		}
+
+		renderer, err := p.rendererFor(renderers, filePath)
+		if err != nil {
+			return err
+		}
+		var licenseContent string
+		if renderer != nil {
+			// Without the owner or other values of the header, the first
+			// copy is kept
+			content, err := renderer.render(filePath, p.opts.Year)
+			if err != nil && !errors.Is(err, ErrUnresolvedPlaceholder) {
+				return err
+			}
+			licenseContent = content
+		}
+
+		changed, err := p.DedupeLicenseHeaders(filePath, licenseContent, commentSyntax)
+		if err != nil {
+			return err
+		}
+		if changed {
+			result.Changed = append(result.Changed, filePath)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// DedupeLicenseHeaders collapses consecutive copies of the license header at
+// the top of the file into one, reporting whether the file was changed
+func (p *Processor) DedupeLicenseHeaders(filePath, licenseContent string, commentSyntax CommentSyntax) (bool, error) {
+	// Read the existing file content
+	content, err := readText(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	// Keep the byte order mark, shebangs and similar preambles in place
+	bom, text := splitBOM(content)
+	preamble, rest := splitPreamble(text, filePath)
+	var header string
+	if licenseContent != "" {
+		header = withLineEnding(p.formatHeader(filePath, licenseContent, commentSyntax), lineEnding(text))
+	}
+	newRest, changed := dedupeHeaders(rest, header, commentSyntax)
+	if !changed {
+		// The headers may follow a file-level docstring
+		doc, afterDoc := splitDocstring(rest, filePath)
+		if afterDoc, changed = dedupeHeaders(afterDoc, header, commentSyntax); !changed {
+			return false, nil
+		}
+		newRest = doc + afterDoc
+	}
+	return true, p.writeFile(filePath, content, bom+preamble+newRest)
+}
+
+// dedupeHeaders drops the license headers at the start of content that
+// repeat the one before them, preferring header among the copies
+func dedupeHeaders(content, header string, commentSyntax CommentSyntax) (string, bool) {
+	copies, rest := leadingHeaders(content, commentSyntax)
+	header = strings.TrimRight(header, "\r\n")
+
+	var kept []headerCopy
+	for _, c := range copies {
+		if len(kept) == 0 || normalizeHeader(c.text) != normalizeHeader(kept[len(kept)-1].text) {
+			kept = append(kept, c)
+			continue
+		}
+
+		// Keep the canonical copy and the separator of the last one
+		last := &kept[len(kept)-1]
+		if header != "" && strings.TrimRight(c.text, "\r\n") == header {
+			last.text = c.text
+		}
+		last.separator = c.separator
+	}
+	if len(kept) == len(copies) {
+		return content, false
+	}
+
+	var b strings.Builder
+	for _, c := range kept {
+		b.WriteString(c.text + c.separator)
+	}
+	return b.String() + rest, true
+}
+
+// leadingHeaders splits the comment blocks reading like a license notice off
+// the start of content. Copies of a header written as line comments without
+// a blank line between them are split apart.
+func leadingHeaders(content string, commentSyntax CommentSyntax) ([]headerCopy, string) {
+	var copies []headerCopy
+	for {
+		end := leadingCommentLength(content, commentSyntax)
+		if end == 0 || !looksLikeLicense(content[:end]) {
+			return copies, content
+		}
+		block := content[:end]
+		rest := content[end:]
+		separator := rest[:len(rest)-len(strings.TrimLeft(rest, "\r\n"))]
+		content = rest[len(separator):]
+
+		parts := repeatedLines(block)
+		for i, part := range parts {
+			c := headerCopy{text: part}
+			if i == len(parts)-1 {
+				c.separator = separator
+			}
+			copies = append(copies, c)
+		}
+	}
+}
+
+// repeatedLines splits block into the repeats of its shortest run of lines
+// reading like a license notice, e.g. a line-comment header written twice
+// without a blank line in between, or returns block alone
+func repeatedLines(block string) []string {
+	lines := strings.SplitAfter(block, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for period := 1; period <= len(lines)/2; period++ {
+		if len(lines)%period != 0 || !looksLikeLicense(strings.Join(lines[:period], "")) {
+			continue
+		}
+		repeated := true
+		for i := period; i < len(lines) && repeated; i++ {
+			repeated = normalizeHeader(lines[i]) == normalizeHeader(lines[i%period])
+		}
+		if !repeated {
+			continue
+		}
+		var parts []string
+		for i := 0; i < len(lines); i += period {
+			parts = append(parts, strings.Join(lines[i:i+period], ""))
+		}
+		return parts
+	}
+	return []string{block}
+}
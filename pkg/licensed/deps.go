@@ -0,0 +1,434 @@
+package licensed
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DependencyPolicy restricts the licenses the dependencies of the project
+// may use. Entries are SPDX identifiers or catalog names, optionally ending
+// in a * wildcard such as GPL-*, and "copyleft" stands for every copyleft
+// license.
+type DependencyPolicy struct {
+	// Allow lists the only licenses dependencies may use, if not empty
+	Allow []string `yaml:"allow,omitempty" toml:"allow,omitempty"`
+	// Deny lists licenses dependencies must not use
+	Deny []string `yaml:"deny,omitempty" toml:"deny,omitempty"`
+	// NodeModules also reports the packages installed in node_modules
+	NodeModules bool `yaml:"node_modules,omitempty" toml:"node_modules,omitempty"`
+}
+
+// Dependency is a third-party module or package of the project
+type Dependency struct {
+	// Name is the module path or package name
+	Name    string
+	Version string
+	// Ecosystem is "go" or "npm"
+	Ecosystem string
+	// License is the SPDX identifier or expression of the license,
+	// "unknown" for an unrecognized license text, or empty if none was
+	// found
+	License string
+	// LicenseFile is the file the license was read from, if any
+	LicenseFile string
+	// Copyleft is set if a license of the dependency is a copyleft license
+	Copyleft bool
+	// Violation tells why the license breaks the policy, empty if it
+	// complies
+	Violation string
+}
+
+// copyleftPrefixes start the SPDX identifiers of the copyleft licenses
+var copyleftPrefixes = []string{"GPL-", "AGPL-", "LGPL-", "MPL-", "EPL-", "EUPL-", "CDDL-", "OSL-", "CC-BY-SA-", "SSPL-"}
+
+// Dependencies reports the license of every dependency of the project: the
+// modules required by go.mod or listed in go.sum, read from vendor/ or the
+// module cache, and with the NodeModules policy setting the packages in
+// node_modules. Each is checked against the Dependencies policy.
+func (p *Processor) Dependencies() ([]Dependency, error) {
+	known, err := p.knownLicenses()
+	if err != nil {
+		return nil, err
+	}
+
+	deps, err := p.goDependencies(known)
+	if err != nil {
+		return nil, err
+	}
+	if p.opts.Dependencies.NodeModules {
+		npmDeps, err := p.npmDependencies(known)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, npmDeps...)
+	}
+
+	for i := range deps {
+		deps[i].Copyleft = isCopyleft(deps[i].License)
+		deps[i].Violation = p.opts.Dependencies.check(deps[i].License)
+	}
+	sort.SliceStable(deps, func(i, j int) bool {
+		if deps[i].Ecosystem != deps[j].Ecosystem {
+			return deps[i].Ecosystem < deps[j].Ecosystem
+		}
+		return deps[i].Name < deps[j].Name
+	})
+	return deps, nil
+}
+
+// goDependencies lists the modules of go.mod and go.sum, with the license
+// of their source in vendor/, a local replacement or the module cache
+func (p *Processor) goDependencies(known []knownLicense) ([]Dependency, error) {
+	modules, replacements, err := readGoMod(filepath.Join(p.opts.Dir, "go.mod"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// go.sum also lists the modules required indirectly, those with only a
+	// go.mod hash are not built
+	versions := make(map[string]string)
+	for _, module := range modules {
+		versions[module.Name] = module.Version
+	}
+	sum, err := os.ReadFile(filepath.Join(p.opts.Dir, "go.sum"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(sum), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		if _, ok := versions[fields[0]]; !ok {
+			modules = append(modules, Dependency{Name: fields[0], Version: fields[1], Ecosystem: "go"})
+			versions[fields[0]] = fields[1]
+		}
+	}
+
+	modCache := goModCache()
+	for i, module := range modules {
+		// The module may be found in several places, the first one wins
+		var dirs []string
+		if replacement, ok := replacements[module.Name]; ok {
+			if strings.HasPrefix(replacement.Name, ".") || filepath.IsAbs(replacement.Name) {
+				dir := filepath.FromSlash(replacement.Name)
+				if !filepath.IsAbs(dir) {
+					dir = filepath.Join(p.opts.Dir, dir)
+				}
+				dirs = append(dirs, dir)
+			} else {
+				module = replacement
+			}
+		}
+		dirs = append(dirs, filepath.Join(p.opts.Dir, "vendor", filepath.FromSlash(modules[i].Name)))
+		if modCache != "" {
+			dirs = append(dirs, filepath.Join(modCache, escapeModulePath(module.Name)+"@"+escapeModulePath(module.Version)))
+		}
+		for _, dir := range dirs {
+			if license, file := detectLicenseDir(dir, known); file != "" {
+				modules[i].License, modules[i].LicenseFile = license, file
+				break
+			}
+		}
+		if modules[i].LicenseFile == "" {
+			p.log.Debug("no license file found", "module", module.Name, "version", module.Version)
+		}
+	}
+	return modules, nil
+}
+
+// readGoMod returns the required modules of a go.mod file, and the
+// replacements of its replace directives by module path
+func readGoMod(path string) ([]Dependency, map[string]Dependency, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var modules []Dependency
+	replacements := make(map[string]Dependency)
+	var block string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case block != "" && fields[0] == ")":
+			block = ""
+		case block != "":
+			modules = parseGoModLine(block, fields, modules, replacements)
+		case len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+		default:
+			modules = parseGoModLine(fields[0], fields[1:], modules, replacements)
+		}
+	}
+	return modules, replacements, scanner.Err()
+}
+
+// parseGoModLine adds the entry of a require or replace directive
+func parseGoModLine(directive string, fields []string, modules []Dependency, replacements map[string]Dependency) []Dependency {
+	switch directive {
+	case "require":
+		if len(fields) >= 2 {
+			modules = append(modules, Dependency{Name: unquote(fields[0]), Version: fields[1], Ecosystem: "go"})
+		}
+	case "replace":
+		// old [version] => new [version]
+		arrow := -1
+		for i, field := range fields {
+			if field == "=>" {
+				arrow = i
+			}
+		}
+		if arrow < 1 || arrow+1 >= len(fields) {
+			break
+		}
+		replacement := Dependency{Name: unquote(fields[arrow+1]), Ecosystem: "go"}
+		if arrow+2 < len(fields) {
+			replacement.Version = fields[arrow+2]
+		}
+		replacements[unquote(fields[0])] = replacement
+	}
+	return modules
+}
+
+// unquote strips the quotes of a quoted go.mod module path
+func unquote(s string) string {
+	return strings.Trim(s, "\"`")
+}
+
+// goModCache returns the module cache directory, GOMODCACHE or
+// GOPATH/pkg/mod
+func goModCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	if gopath := filepath.SplitList(os.Getenv("GOPATH")); len(gopath) > 0 && gopath[0] != "" {
+		return filepath.Join(gopath[0], "pkg", "mod")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, "go", "pkg", "mod")
+	}
+	return ""
+}
+
+// escapeModulePath escapes a module path or version as the module cache
+// does, replacing every upper case letter by an exclamation mark and its
+// lower case
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return filepath.FromSlash(b.String())
+}
+
+// npmDependencies lists the packages installed in node_modules, with the
+// license of their package.json or else of their license file
+func (p *Processor) npmDependencies(known []knownLicense) ([]Dependency, error) {
+	root := filepath.Join(p.opts.Dir, "node_modules")
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Scoped packages are one level deeper, e.g. @types/node
+	var dirs []string
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case strings.HasPrefix(name, "."):
+		case strings.HasPrefix(name, "@"):
+			scoped, err := os.ReadDir(filepath.Join(root, name))
+			if err != nil {
+				return nil, err
+			}
+			for _, scopedEntry := range scoped {
+				dirs = append(dirs, filepath.Join(root, name, scopedEntry.Name()))
+			}
+		default:
+			dirs = append(dirs, filepath.Join(root, name))
+		}
+	}
+
+	var deps []Dependency
+	for _, dir := range dirs {
+		data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var manifest struct {
+			Name     string          `json:"name"`
+			Version  string          `json:"version"`
+			License  json.RawMessage `json:"license"`
+			Licenses []struct {
+				Type string `json:"type"`
+			} `json:"licenses"`
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			p.log.Warn("cannot parse package.json", "path", filepath.Join(dir, "package.json"), "error", err)
+			continue
+		}
+
+		dep := Dependency{Name: manifest.Name, Version: manifest.Version, Ecosystem: "npm"}
+		if dep.Name == "" {
+			dep.Name = filepath.ToSlash(strings.TrimPrefix(dir, root+string(filepath.Separator)))
+		}
+		// The license is a string, {"type": ...}, or a legacy list
+		var license struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(manifest.License, &dep.License) != nil && json.Unmarshal(manifest.License, &license) == nil {
+			dep.License = license.Type
+		}
+		if dep.License == "" {
+			var types []string
+			for _, legacy := range manifest.Licenses {
+				types = append(types, legacy.Type)
+			}
+			dep.License = strings.Join(types, " OR ")
+		}
+		if dep.License != "" {
+			dep.LicenseFile = filepath.Join(dir, "package.json")
+		} else {
+			dep.License, dep.LicenseFile = detectLicenseDir(dir, known)
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// detectLicenseDir classifies the license file of a dependency directory,
+// returning an empty file if it has none
+func detectLicenseDir(dir string, known []knownLicense) (license, file string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", ""
+	}
+	for _, entry := range entries {
+		name := strings.ToUpper(entry.Name())
+		if entry.IsDir() || !(strings.HasPrefix(name, "LICENSE") || strings.HasPrefix(name, "LICENCE") || strings.HasPrefix(name, "COPYING")) {
+			continue
+		}
+		file = filepath.Join(dir, entry.Name())
+		text, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		return classifyLicense(string(text), known), file
+	}
+	return "", ""
+}
+
+// classifyLicense returns the SPDX identifier of a license text, or
+// "unknown" if it resembles none of the known licenses
+func classifyLicense(text string, known []knownLicense) string {
+	if match := spdxTag.FindStringSubmatch(text); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+	license, best := "unknown", 0.0
+	pairs := wordPairs(text)
+	for _, candidate := range known {
+		if similarity := diceCoefficient(pairs, candidate.pairs); similarity >= minSimilarity && similarity > best {
+			license, best = candidate.id, similarity
+		}
+	}
+	return license
+}
+
+// isCopyleft reports whether a license of an SPDX expression is a copyleft
+// license
+func isCopyleft(expression string) bool {
+	names, err := licenseNames(expression)
+	if err != nil {
+		names = []string{expression}
+	}
+	for _, name := range names {
+		id := strings.ToUpper(SPDXID(name))
+		for _, prefix := range copyleftPrefixes {
+			if strings.HasPrefix(id, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// check returns why a license breaks the policy, or "" if it complies. One
+// license of an expression with OR complying is enough, otherwise all must.
+func (policy DependencyPolicy) check(expression string) string {
+	if expression == "" || expression == "unknown" {
+		if len(policy.Allow) > 0 {
+			return "no recognized license"
+		}
+		return ""
+	}
+
+	names, err := licenseNames(expression)
+	if err != nil {
+		names = []string{expression}
+	}
+	alternatives := strings.Contains(strings.ToUpper(" "+expression+" "), " OR ")
+	var violations []string
+	for _, name := range names {
+		violation := ""
+		switch {
+		case matchesLicense(policy.Deny, name):
+			violation = name + " is denied"
+		case len(policy.Allow) > 0 && !matchesLicense(policy.Allow, name):
+			violation = name + " is not allowed"
+		}
+		if violation == "" && alternatives {
+			return ""
+		}
+		if violation != "" {
+			violations = append(violations, violation)
+		}
+	}
+	return strings.Join(violations, ", ")
+}
+
+// matchesLicense reports whether a license is one of the policy entries,
+// ignoring the -only suffix of the GNU licenses
+func matchesLicense(entries []string, license string) bool {
+	normalize := func(name string) string {
+		return strings.TrimSuffix(strings.ToLower(SPDXID(name)), "-only")
+	}
+	for _, entry := range entries {
+		switch {
+		case strings.EqualFold(entry, "copyleft"):
+			if isCopyleft(license) {
+				return true
+			}
+		case strings.HasSuffix(entry, "*"):
+			if strings.HasPrefix(strings.ToLower(SPDXID(license)), strings.ToLower(strings.TrimSuffix(entry, "*"))) {
+				return true
+			}
+		case normalize(entry) == normalize(license):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package licensed
+
+import (
+	"regexp"
+	"strings"
+)
+
+// spdxTag matches an SPDX-License-Identifier tag and captures its expression
+var spdxTag = regexp.MustCompile(`SPDX-License-Identifier:[ \t]*([^\r\n]+)`)
+
+// minSimilarity is the similarity a header needs to a license text to be
+// classified as that license
+const minSimilarity = 0.75
+
+// Detection is the license found in the header of a file
+type Detection struct {
+	// Path is the file the header was read from
+	Path string
+	// License is the SPDX identifier of the detected license, "unknown"
+	// for an unrecognized license notice or empty without a header
+	License string
+	// Similarity rates from 0 to 1 how closely the header matches the text
+	// of the detected license, 1 for an SPDX-License-Identifier tag
+	Similarity float64
+	// Mismatch is set if the license differs from the configured one
+	Mismatch bool
+	// Copyright are the copyright lines of the header, without the comment
+	// syntax
+	Copyright []string
+}
+
+// knownLicense is a license text broken into word pairs for comparison
+type knownLicense struct {
+	id    string
+	pairs map[string]bool
+}
+
+// Detect classifies the license header of every file of the project
+// against the texts of the catalog, flagging the files whose license
+// differs from License
+func (p *Processor) Detect() (*Result, error) {
+	known, err := p.knownLicenses()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	err = p.forEachFile(result, func(filePath string) error {
+		commentSyntax, ok := p.commentSyntaxFor(filePath)
+		if !ok {
+			p.skipFile(result, "unknown type", filePath)
+			return nil
+		}
+
+		content, err := readText(filePath)
+		if err != nil {
+			return err
+		}
+		detection := detectLicense(content, filePath, commentSyntax, known)
+
+		// Flag headers naming another license than the configured one
+		opts, _ := p.optionsFor(filePath)
+		if opts.License != "" && detection.License != "" {
+			detection.Mismatch = !strings.EqualFold(detection.License, SPDXID(opts.License))
+		}
+		result.Detections = append(result.Detections, detection)
+		return nil
+	})
+	return result, err
+}
+
+// DetectProjectLicense classifies the LICENSE, LICENCE or COPYING file of
+// dir against the catalog and the texts of licenseDir. It returns the SPDX
+// identifier of its license and the path of the file, or an empty license
+// if there is no such file or it resembles no known license.
+func DetectProjectLicense(dir, licenseDir string) (string, string, error) {
+	known, err := loadKnownLicenses(licenseDir)
+	if err != nil {
+		return "", "", err
+	}
+	license, file := detectLicenseDir(dir, known)
+	if license == "unknown" {
+		license = ""
+	}
+	return license, file, nil
+}
+
+// knownLicenses returns the texts of the catalog and the license directory
+// broken into word pairs, along with the short notices for file headers
+func (p *Processor) knownLicenses() ([]knownLicense, error) {
+	return loadKnownLicenses(p.opts.LicenseDir)
+}
+
+func loadKnownLicenses(licenseDir string) ([]knownLicense, error) {
+	infos, err := Licenses(licenseDir)
+	if err != nil {
+		return nil, err
+	}
+	var known []knownLicense
+	for _, info := range infos {
+		meta, text, err := readLicenseFile(info.Name, licenseDir)
+		if err != nil {
+			return nil, err
+		}
+		known = append(known, knownLicense{id: info.SPDXID, pairs: wordPairs(string(text))})
+		if meta.Header != "" {
+			known = append(known, knownLicense{id: info.SPDXID, pairs: wordPairs(meta.Header)})
+		}
+	}
+	return known, nil
+}
+
+// detectLicense classifies the leading comment block of content
+func detectLicense(content, filePath string, commentSyntax CommentSyntax, known []knownLicense) Detection {
+	detection := Detection{Path: filePath}
+	_, text := splitBOM(content)
+	_, rest := splitPreamble(text, filePath)
+	header := rest[:leadingCommentLength(rest, commentSyntax)]
+	if !looksLikeLicense(header) && !spdxTag.MatchString(header) {
+		// The header may follow a file-level docstring
+		_, rest = splitDocstring(rest, filePath)
+		header = rest[:leadingCommentLength(rest, commentSyntax)]
+	}
+	detection.Copyright = copyrightLines(header, commentSyntax)
+
+	// An SPDX tag names the license outright
+	if match := spdxTag.FindStringSubmatch(header); match != nil {
+		expression := strings.TrimSpace(match[1])
+		if commentSyntax.BlockClose != "" {
+			expression = strings.TrimSpace(strings.TrimSuffix(expression, commentSyntax.BlockClose))
+		}
+		detection.License = expression
+		detection.Similarity = 1
+		return detection
+	}
+	if !looksLikeLicense(header) {
+		return detection
+	}
+
+	// Otherwise pick the license text sharing the most word pairs
+	detection.License = "unknown"
+	pairs := wordPairs(header)
+	for _, license := range known {
+		if similarity := diceCoefficient(pairs, license.pairs); similarity > detection.Similarity {
+			detection.Similarity = similarity
+			if similarity >= minSimilarity {
+				detection.License = license.id
+			}
+		}
+	}
+	return detection
+}
+
+// copyrightLines returns the copyright notices of a comment block, taking
+// the text of SPDX-FileCopyrightText tags
+func copyrightLines(header string, commentSyntax CommentSyntax) []string {
+	var lines []string
+	for _, line := range strings.Split(header, "\n") {
+		line = strings.TrimSpace(line)
+		for _, token := range []string{commentSyntax.LinePrefix, commentSyntax.BlockOpen, strings.TrimSpace(commentSyntax.BlockDecoration)} {
+			if token != "" {
+				line = strings.TrimSpace(strings.TrimPrefix(line, token))
+			}
+		}
+		if commentSyntax.BlockClose != "" {
+			line = strings.TrimSpace(strings.TrimSuffix(line, commentSyntax.BlockClose))
+		}
+		if text, ok := strings.CutPrefix(line, "SPDX-FileCopyrightText:"); ok {
+			line = strings.TrimSpace(text)
+		} else if !strings.HasPrefix(strings.ToLower(line), "copyright") {
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// sameLicense reports whether the comment block of an existing header is an
+// outdated version of the header of licenseContent: it carries an SPDX tag
+// for license, or shares most of its wording
+func sameLicense(block, licenseContent, license string) bool {
+	if match := spdxTag.FindStringSubmatch(block); match != nil && license != "" {
+		return strings.EqualFold(strings.Fields(match[1])[0], SPDXID(license))
+	}
+	return looksLikeLicense(block) && diceCoefficient(wordPairs(block), wordPairs(licenseContent)) >= minSimilarity
+}
+
+// wordPairs returns the set of adjacent word pairs of a text, ignoring
+// case, punctuation, comment characters and years
+func wordPairs(text string) map[string]bool {
+	words := strings.Fields(normalizeHeader(text))
+	pairs := make(map[string]bool)
+	for i := 0; i+1 < len(words); i++ {
+		pairs[words[i]+" "+words[i+1]] = true
+	}
+	return pairs
+}
+
+// diceCoefficient rates the overlap of two sets from 0 to 1
+func diceCoefficient(a, b map[string]bool) float64 {
+	if len(a)+len(b) == 0 {
+		return 0
+	}
+	var shared int
+	for pair := range a {
+		if b[pair] {
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
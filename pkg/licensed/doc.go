@@ -0,0 +1,21 @@
+// Package licensed adds, checks, removes and updates license headers in the
+// source files of a project.
+//
+// A Processor is created from Options naming the license, copyright owner,
+// year and project directory:
+//
+//	p, err := licensed.New(licensed.Options{
+//		License: "mit",
+//		Owners:  []licensed.Owner{{Name: "Jane Doe"}},
+//		Year:    "2024",
+//		Dir:     ".",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	result, err := p.Add()
+//
+// The files visited honor the .licensed-ignore and .gitignore files of the
+// project and its subdirectories, and comment syntaxes are looked up by file extension from the
+// bundled comment-syntax.txt, overridable by the project's own.
+package licensed
@@ -0,0 +1,256 @@
+package licensed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnosis is the outcome of one check of Diagnose
+type Diagnosis struct {
+	// Check names what was checked, e.g. "ignore patterns"
+	Check string
+	// Err is the problem found, nil if the check passed
+	Err error
+	// Hint tells how to fix the problem
+	Hint string
+	// Warning marks problems that only disable some features
+	Warning bool
+}
+
+// Diagnose checks the project configuration and the environment licensed
+// runs in with opts: the configuration file, the ignore and include
+// patterns, the comment syntax overrides, the license or template of the
+// project and of its path overrides, and the git integration. Every problem
+// found gets its own Diagnosis, and each check without a problem one with a
+// nil Err.
+func Diagnose(opts Options) []Diagnosis {
+	var diagnoses []Diagnosis
+	report := func(check string, err error, hint string) {
+		diagnoses = append(diagnoses, Diagnosis{Check: check, Err: err, Hint: hint})
+	}
+	warn := func(check string, err error, hint string) {
+		diagnoses = append(diagnoses, Diagnosis{Check: check, Err: err, Hint: hint, Warning: true})
+	}
+	// pass records a check that found no problem
+	pass := func(check string) {
+		for _, diagnosis := range diagnoses {
+			if diagnosis.Check == check {
+				return
+			}
+		}
+		diagnoses = append(diagnoses, Diagnosis{Check: check})
+	}
+	if opts.Dir == "" {
+		opts.Dir = "."
+	}
+
+	// The configuration file must parse and only hold known keys
+	path, err := checkConfig(opts.Dir)
+	if err != nil {
+		report("config file", err, "fix "+filepath.Base(path)+" or run licensed init --force to write a new one")
+	} else if path == "" {
+		warn("config file", errors.New("no config file"), "run licensed init to record the license settings in .licensed.yaml")
+	}
+	pass("config file")
+	if _, path, _ := LoadUserConfig(); path != "" {
+		if err := checkConfigFile(path); err != nil {
+			report("user config file", err, "fix "+path)
+		}
+		pass("user config file")
+	}
+
+	// The problems New logs are reported below
+	opts.Logger = nil
+	p, err := New(opts)
+	if err != nil {
+		report("settings", err, "fix the setting in the config file or on the command line")
+		return append(diagnoses, diagnoseGit(opts.Dir))
+	}
+	pass("settings")
+
+	// Invalid patterns are otherwise only warned about, or silently dropped
+	// for path overrides
+	checkPatterns := func(source string, patterns []string) {
+		for _, pattern := range patterns {
+			if _, _, err := parseIgnoreRule(pattern); err != nil {
+				report("ignore patterns", fmt.Errorf("invalid pattern %q in %s: %w", strings.TrimSpace(pattern), source, err), "fix the pattern, escaping literal [ and ] with a backslash")
+			}
+		}
+	}
+	projectIgnoreFile, _ := os.ReadFile(filepath.Join(opts.Dir, ".licensed-ignore"))
+	checkPatterns(".licensed-ignore", strings.Split(string(projectIgnoreFile), "\n"))
+	checkPatterns("ignore patterns", opts.IgnorePatterns)
+	checkPatterns("include patterns", opts.IncludePatterns)
+	for _, override := range opts.Overrides {
+		if strings.TrimSpace(override.Pattern) == "" {
+			report("ignore patterns", errors.New("path override without a path"), "give every entry of paths a path pattern")
+		}
+		checkPatterns("paths", []string{override.Pattern})
+	}
+	pass("ignore patterns")
+
+	// Comment syntax lines with the wrong number of fields are dropped
+	projectCommentSyntaxFile, _ := os.ReadFile(filepath.Join(opts.Dir, "comment-syntax.txt"))
+	for i, line := range strings.Split(string(projectCommentSyntaxFile), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) != 2 && len(fields) != 4 && len(fields) != 5 {
+			report("comment syntax", fmt.Errorf("comment-syntax.txt:%d: %q has %d fields", i+1, line, len(fields)), "write <ext>:<line_prefix> or <ext>:<line_prefix>:<block_open>:<block_close>[:<block_decoration>]")
+			continue
+		}
+		if err := checkCommentSyntax(fields[0], ParseCommentSyntax([]byte(line))[strings.ToLower(fields[0])]); err != nil {
+			report("comment syntax", fmt.Errorf("comment-syntax.txt:%d: %w", i+1, err), "set a line prefix, or both a block opening and closing")
+		}
+	}
+	for ext, syntax := range opts.CommentSyntaxes {
+		if !syntax.hasDelimiters() && (syntax.Width != 0 || syntax.Banner != "") && p.commentSyntaxes[strings.ToLower(ext)].hasDelimiters() {
+			continue
+		}
+		if err := checkCommentSyntax(ext, syntax); err != nil {
+			report("comment syntax", fmt.Errorf("config comments: %w", err), "set line, or both block_open and block_close")
+		}
+	}
+	pass("comment syntax")
+
+	// The header text of the project and of every path override must render
+	if opts.License == "" && opts.Template == "" && opts.LicenseTextFile == "" && opts.HeaderFile == "" && !opts.CopyrightOnly {
+		report("license", errors.New("no license or template configured"), "set license in .licensed.yaml or pass --license")
+	} else if err := p.checkHeader(opts); err != nil {
+		report("license", err, headerHint(err))
+	}
+	for _, override := range p.overrides {
+		overrideOpts := opts
+		if override.License != "" {
+			overrideOpts.License, overrideOpts.Template, overrideOpts.LicenseTextFile, overrideOpts.HeaderFile = override.License, "", "", ""
+		}
+		if len(override.Owners) > 0 {
+			overrideOpts.Owners = override.Owners
+		}
+		if override.Template != "" {
+			overrideOpts.Template, overrideOpts.HeaderFile = override.Template, ""
+		}
+		if overrideOpts.License == "" && overrideOpts.Template == "" && overrideOpts.LicenseTextFile == "" && overrideOpts.HeaderFile == "" && !overrideOpts.CopyrightOnly {
+			continue
+		}
+		if err := p.checkHeader(overrideOpts); err != nil {
+			report("license", fmt.Errorf("path %s: %w", override.Pattern, err), headerHint(err))
+		}
+	}
+	pass("license")
+	if len(opts.Owners) == 0 {
+		warn("copyright holder", errors.New("no copyright holder"), "set owner in .licensed.yaml, pass --name or set git config user.name")
+	}
+	pass("copyright holder")
+
+	return append(diagnoses, diagnoseGit(opts.Dir))
+}
+
+// checkConfig parses the configuration file of dir strictly, rejecting keys
+// LoadConfig would ignore, and returns its path
+func checkConfig(dir string) (string, error) {
+	_, path, err := LoadConfig(dir)
+	if err != nil || path == "" {
+		return path, err
+	}
+	return path, checkConfigFile(path)
+}
+
+// checkConfigFile parses a configuration file strictly
+func checkConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var cfg Config
+	if filepath.Ext(path) == ".toml" {
+		md, err := toml.Decode(string(data), &cfg)
+		if err != nil {
+			return err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown key %s", path, undecoded[0])
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.OnConflict != "" && !slices.Contains(ConflictStrategies, cfg.OnConflict) {
+		return fmt.Errorf("%s: on_conflict must be one of %s, not %q", path, strings.Join(ConflictStrategies, ", "), cfg.OnConflict)
+	}
+	return nil
+}
+
+// checkCommentSyntax reports a comment syntax that cannot write a header
+func checkCommentSyntax(ext string, syntax CommentSyntax) error {
+	switch {
+	case ext == "" || strings.ContainsAny(ext, `/\ `):
+		return fmt.Errorf("invalid extension %q", ext)
+	case (syntax.BlockOpen == "") != (syntax.BlockClose == ""):
+		return fmt.Errorf("%s: block comment needs both an opening and a closing", ext)
+	case syntax.LinePrefix == "" && syntax.BlockOpen == "":
+		return fmt.Errorf("%s: no line prefix or block comment", ext)
+	}
+	return nil
+}
+
+// checkHeader renders the header of opts once, as Add would
+func (p *Processor) checkHeader(opts Options) error {
+	renderer, err := p.newHeaderRenderer(opts)
+	if err != nil {
+		return err
+	}
+	_, err = renderer.render("", opts.Year)
+	return err
+}
+
+// headerHint tells how to fix a header that does not render
+func headerHint(err error) string {
+	var unknown *UnknownLicenseError
+	switch {
+	case errors.As(err, &unknown) && unknown.Suggestion != "":
+		return "use " + unknown.Suggestion + ", see licensed list"
+	case errors.As(err, &unknown):
+		return "use one of " + strings.Join(unknown.Available, ", ") + ", an SPDX identifier, or add the text to --license-dir"
+	case errors.Is(err, ErrUnresolvedPlaceholder):
+		return "pass the value with its flag, e.g. --owner-email or --project, or set it in .licensed.yaml"
+	case errors.Is(err, os.ErrNotExist):
+		return "check the template or license path"
+	}
+	return "fix the license or template"
+}
+
+// diagnoseGit checks that git is installed and dir is inside a work tree,
+// which --staged, --changed, --year-from-git and install-hook need
+func diagnoseGit(dir string) Diagnosis {
+	diagnosis := Diagnosis{Check: "git", Warning: true}
+	if _, err := exec.LookPath("git"); err != nil {
+		diagnosis.Err, diagnosis.Hint = errors.New("git is not installed"), "install git to use --staged, --changed, --year-from-git and install-hook"
+		return diagnosis
+	}
+	output, err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Output()
+	if err != nil || strings.TrimSpace(string(output)) != "true" {
+		diagnosis.Err, diagnosis.Hint = errors.New("not a git work tree"), "run git init to use --staged, --changed, --year-from-git and install-hook"
+		return diagnosis
+	}
+	if GitUserName(dir) == "" {
+		diagnosis.Err, diagnosis.Hint = errors.New("git config user.name is not set"), "set it with git config user.name or pass --name"
+	}
+	return diagnosis
+}
@@ -0,0 +1,292 @@
+package licensed
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// editorConfig holds the .editorconfig properties that shape inserted
+// headers, empty where no section sets them
+type editorConfig struct {
+	endOfLine   string
+	charset     string
+	indentStyle string
+	indentSize  string
+}
+
+// eol returns the line ending end_of_line declares, or fallback if it
+// declares none. CR line endings are not supported and fall back too.
+func (c editorConfig) eol(fallback string) string {
+	switch c.endOfLine {
+	case "lf":
+		return "\n"
+	case "crlf":
+		return "\r\n"
+	}
+	return fallback
+}
+
+// indent returns one level of indentation as declared by indent_style and
+// indent_size, or "" if indent_style is not set
+func (c editorConfig) indent() string {
+	switch c.indentStyle {
+	case "tab":
+		return "\t"
+	case "space":
+		size, err := strconv.Atoi(c.indentSize)
+		if err != nil || size < 1 {
+			size = 4
+		}
+		return strings.Repeat(" ", size)
+	}
+	return ""
+}
+
+// editorconfigSection is a [glob] section of an .editorconfig file
+type editorconfigSection struct {
+	re    *regexp.Regexp
+	props map[string]string
+}
+
+// editorconfigFile is a parsed .editorconfig file
+type editorconfigFile struct {
+	root     bool
+	sections []editorconfigSection
+}
+
+// editorconfigTree holds the .editorconfig files of the directories looked
+// up, keyed by absolute path, nil where a directory has none. Directories
+// are read the first time a file below them is looked up.
+type editorconfigTree struct {
+	files map[string]*editorconfigFile
+}
+
+func newEditorconfigTree() *editorconfigTree {
+	return &editorconfigTree{files: make(map[string]*editorconfigFile)}
+}
+
+// lookup returns the properties the .editorconfig files set for filePath.
+// As with editors, the files of the ancestor directories are read up to the
+// one declaring root = true, the closest ones taking precedence.
+func (t *editorconfigTree) lookup(filePath string) editorConfig {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return editorConfig{}
+	}
+
+	// Collect the files from the closest directory up
+	var dirs []string
+	var files []*editorconfigFile
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		if file := t.file(dir); file != nil {
+			dirs = append(dirs, dir)
+			files = append(files, file)
+			if file.root {
+				break
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	props := make(map[string]string)
+	for i := len(files) - 1; i >= 0; i-- {
+		rel, err := filepath.Rel(dirs[i], abs)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, section := range files[i].sections {
+			if !section.re.MatchString(rel) {
+				continue
+			}
+			for key, value := range section.props {
+				props[key] = value
+			}
+		}
+	}
+	config := editorConfig{
+		endOfLine:   props["end_of_line"],
+		charset:     props["charset"],
+		indentStyle: props["indent_style"],
+		indentSize:  props["indent_size"],
+	}
+	if config.indentSize == "tab" {
+		config.indentStyle = "tab"
+	}
+	return config
+}
+
+// file returns the parsed .editorconfig of dir, reading it on first use
+func (t *editorconfigTree) file(dir string) *editorconfigFile {
+	file, ok := t.files[dir]
+	if !ok {
+		if data, err := os.ReadFile(filepath.Join(dir, ".editorconfig")); err == nil {
+			file = parseEditorconfig(data)
+		}
+		t.files[dir] = file
+	}
+	return file
+}
+
+// parseEditorconfig parses an .editorconfig file, skipping the sections
+// whose glob cannot be compiled. Keys and values are lowercased, and
+// "unset" values are kept to undo those of farther files.
+func parseEditorconfig(data []byte) *editorconfigFile {
+	file := &editorconfigFile{}
+	var section *editorconfigSection
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = nil
+			if re, err := editorconfigGlob(line[1 : len(line)-1]); err == nil {
+				file.sections = append(file.sections, editorconfigSection{re: re, props: make(map[string]string)})
+				section = &file.sections[len(file.sections)-1]
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+		switch {
+		case section != nil:
+			section.props[key] = value
+		case key == "root":
+			file.root = value == "true"
+		}
+	}
+	return file
+}
+
+// editorconfigGlob compiles an .editorconfig section glob into a regular
+// expression matching paths relative to the file's directory. Globs without
+// a slash match files at any depth. Besides *, **, ? and [classes], braces
+// hold alternatives as in {js,ts} or integer ranges as in {1..3}.
+func editorconfigGlob(glob string) (*regexp.Regexp, error) {
+	switch {
+	case strings.HasPrefix(glob, "/"):
+		glob = glob[1:]
+	case !strings.Contains(glob, "/"):
+		glob = "**/" + glob
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	depth := 0
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '{':
+			end := strings.IndexByte(glob[i+1:], '}')
+			if end >= 0 {
+				if alternatives, ok := numericRange(glob[i+1 : i+1+end]); ok {
+					b.WriteString(alternatives)
+					i += end + 1
+					continue
+				}
+			}
+			b.WriteString("(?:")
+			depth++
+		case c == ',' && depth > 0:
+			b.WriteString("|")
+		case c == '}' && depth > 0:
+			b.WriteString(")")
+			depth--
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// numericRange turns the body of a {n1..n2} brace into a regular expression
+// alternation of the integers it spans
+func numericRange(body string) (string, bool) {
+	from, to, ok := strings.Cut(body, "..")
+	if !ok {
+		return "", false
+	}
+	lo, err := strconv.Atoi(from)
+	if err != nil {
+		return "", false
+	}
+	hi, err := strconv.Atoi(to)
+	if err != nil {
+		return "", false
+	}
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	if hi-lo > 1000 {
+		return `[+-]?\d+`, true
+	}
+	numbers := make([]string, 0, hi-lo+1)
+	for n := lo; n <= hi; n++ {
+		numbers = append(numbers, strconv.Itoa(n))
+	}
+	return "(?:" + strings.Join(numbers, "|") + ")", true
+}
+
+// editorconfigFor returns the .editorconfig properties of filePath, none
+// with NoEditorConfig
+func (p *Processor) editorconfigFor(filePath string) editorConfig {
+	if p.editorconfig == nil {
+		return editorConfig{}
+	}
+	return p.editorconfig.lookup(filePath)
+}
+
+// encodeLatin1 encodes text in ISO-8859-1 for files declaring charset =
+// latin1, replacing the characters it cannot represent with '?'
+func encodeLatin1(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if r > 0xFF {
+			r = '?'
+		}
+		b.WriteByte(byte(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,155 @@
+package licensed
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"unicode/utf16"
+)
+
+// Encodings of text files other than UTF-8, recognized by their byte order
+// mark. UTF-16 files are transcoded to UTF-8 when read and back when
+// written; UTF-32 files are skipped.
+const (
+	encodingUTF16LE = "utf-16le"
+	encodingUTF16BE = "utf-16be"
+	encodingUTF32LE = "utf-32le"
+	encodingUTF32BE = "utf-32be"
+)
+
+// ErrUnsupportedEncoding is returned for files in an encoding that cannot be
+// transcoded without loss, such as UTF-16 with unpaired surrogates
+var ErrUnsupportedEncoding = errors.New("unsupported encoding")
+
+// sniffEncoding returns the encoding announced by the byte order mark at the
+// start of data, or "" for UTF-8 and files without one
+func sniffEncoding(data []byte) string {
+	switch {
+	case len(data) >= 4 && data[0] == 0xFF && data[1] == 0xFE && data[2] == 0 && data[3] == 0:
+		return encodingUTF32LE
+	case len(data) >= 4 && data[0] == 0 && data[1] == 0 && data[2] == 0xFE && data[3] == 0xFF:
+		return encodingUTF32BE
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE:
+		return encodingUTF16LE
+	case len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF:
+		return encodingUTF16BE
+	}
+	return ""
+}
+
+// fileEncoding returns the encoding of filePath according to its byte order
+// mark
+func fileEncoding(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	bom := make([]byte, 4)
+	n, err := io.ReadFull(f, bom)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return sniffEncoding(bom[:n]), nil
+}
+
+// isUTF16 reports whether encoding is one of the UTF-16 encodings
+func isUTF16(encoding string) bool {
+	return encoding == encodingUTF16LE || encoding == encodingUTF16BE
+}
+
+// decodeText returns data as UTF-8 text along with its encoding. UTF-16 is
+// decoded without its byte order mark; if partial, data may be cut short
+// and the code unit or surrogate it ends in the middle of is dropped. Other
+// data is returned as it is.
+func decodeText(data []byte, partial bool) (string, string, error) {
+	encoding := sniffEncoding(data)
+	if !isUTF16(encoding) {
+		return string(data), encoding, nil
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if encoding == encodingUTF16BE {
+		order = binary.BigEndian
+	}
+	data = data[2:]
+	if len(data)%2 != 0 {
+		if !partial {
+			return "", encoding, fmt.Errorf("%w: %s with an odd number of bytes", ErrUnsupportedEncoding, encoding)
+		}
+		data = data[:len(data)-1]
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	if partial && len(units) > 0 && utf16.IsSurrogate(rune(units[len(units)-1])) && units[len(units)-1] < 0xDC00 {
+		units = units[:len(units)-1]
+	}
+
+	// Unpaired surrogates would not survive the round trip
+	text := string(utf16.Decode(units))
+	if !slices.Equal(utf16.Encode([]rune(text)), units) {
+		return "", encoding, fmt.Errorf("%w: %s with unpaired surrogates", ErrUnsupportedEncoding, encoding)
+	}
+	return text, encoding, nil
+}
+
+// encodeText returns UTF-8 text in encoding, with the byte order mark of
+// UTF-16
+func encodeText(text, encoding string) []byte {
+	if !isUTF16(encoding) {
+		return []byte(text)
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if encoding == encodingUTF16BE {
+		order = binary.BigEndian
+	}
+	units := utf16.Encode([]rune(text))
+	data := make([]byte, 2+2*len(units))
+	order.PutUint16(data, 0xFEFF)
+	for i, unit := range units {
+		order.PutUint16(data[2+2*i:], unit)
+	}
+	return data
+}
+
+// readText returns the content of filePath as UTF-8 text, decoding UTF-16
+func readText(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	text, _, err := decodeText(data, false)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", filePath, err)
+	}
+	return text, nil
+}
+
+// checkEncoding returns why filePath cannot be processed in its encoding,
+// or "" if it can. UTF-16 files must decode without loss.
+func checkEncoding(filePath string) (string, error) {
+	encoding, err := fileEncoding(filePath)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case encoding == encodingUTF32LE || encoding == encodingUTF32BE:
+		return encoding + " is not supported", nil
+	case isUTF16(encoding):
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", err
+		}
+		if _, _, err := decodeText(data, false); err != nil {
+			return err.Error(), nil
+		}
+	}
+	return "", nil
+}
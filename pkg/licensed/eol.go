@@ -0,0 +1,33 @@
+package licensed
+
+import "strings"
+
+// utf8BOM is the UTF-8 byte order mark some editors put at the start of files
+const utf8BOM = "\xef\xbb\xbf"
+
+// splitBOM splits content into its byte order mark, if any, and the rest
+func splitBOM(content string) (string, string) {
+	if strings.HasPrefix(content, utf8BOM) {
+		return utf8BOM, content[len(utf8BOM):]
+	}
+	return "", content
+}
+
+// lineEnding returns the line ending used by most lines of content, "\r\n"
+// or "\n", so a header can be written in the style of the file
+func lineEnding(content string) string {
+	lf := strings.Count(content, "\n")
+	crlf := strings.Count(content, "\r\n")
+	if crlf > lf-crlf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// withLineEnding converts the "\n" line endings of text to eol
+func withLineEnding(text, eol string) string {
+	if eol == "\n" {
+		return text
+	}
+	return strings.ReplaceAll(text, "\n", eol)
+}
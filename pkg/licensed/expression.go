@@ -0,0 +1,118 @@
+package licensed
+
+import (
+	"fmt"
+	"strings"
+)
+
+// licenseToken is a token of an SPDX license expression
+type licenseToken struct {
+	text string
+	// license is set for the license operands, exceptions following WITH
+	// are operands too but not licenses
+	license bool
+}
+
+// tokenizeLicense splits an SPDX license expression such as
+// "MIT OR (Apache-2.0 WITH LLVM-exception)" into tokens and validates its
+// syntax. A plain license name is an expression of a single token.
+func tokenizeLicense(expression string) ([]licenseToken, error) {
+	fields := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression))
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	var tokens []licenseToken
+	var depth int
+	expectOperand, afterWith := true, false
+	for _, field := range fields {
+		switch {
+		case field == "(":
+			if !expectOperand || afterWith {
+				return nil, fmt.Errorf("invalid license expression %q: unexpected (", expression)
+			}
+			depth++
+		case field == ")":
+			if expectOperand || depth == 0 {
+				return nil, fmt.Errorf("invalid license expression %q: unexpected )", expression)
+			}
+			depth--
+		case isLicenseOperator(field):
+			if expectOperand {
+				return nil, fmt.Errorf("invalid license expression %q: unexpected %s", expression, field)
+			}
+			field = strings.ToUpper(field)
+			expectOperand, afterWith = true, field == "WITH"
+			tokens = append(tokens, licenseToken{text: field})
+			continue
+		default:
+			if !expectOperand {
+				return nil, fmt.Errorf("invalid license expression %q: missing operator before %s", expression, field)
+			}
+			tokens = append(tokens, licenseToken{text: field, license: !afterWith})
+			expectOperand, afterWith = false, false
+			continue
+		}
+		tokens = append(tokens, licenseToken{text: field})
+	}
+	if expectOperand || depth != 0 {
+		return nil, fmt.Errorf("invalid license expression %q: incomplete", expression)
+	}
+	return tokens, nil
+}
+
+func isLicenseOperator(field string) bool {
+	switch strings.ToUpper(field) {
+	case "OR", "AND", "WITH":
+		return true
+	}
+	return false
+}
+
+// licenseNames returns the licenses an SPDX license expression is made of,
+// without duplicates, in order
+func licenseNames(expression string) ([]string, error) {
+	tokens, err := tokenizeLicense(expression)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, token := range tokens {
+		if token.license && !seen[strings.ToLower(token.text)] {
+			seen[strings.ToLower(token.text)] = true
+			names = append(names, token.text)
+		}
+	}
+	return names, nil
+}
+
+// catalogName returns the catalog name of a license given by name or by
+// SPDX identifier, or the name itself if it is not in the catalog
+func catalogName(name string) string {
+	if _, ok := catalog[strings.ToLower(name)]; ok {
+		return strings.ToLower(name)
+	}
+	for catalogName, info := range catalog {
+		if strings.EqualFold(info.SPDXID, name) {
+			return catalogName
+		}
+	}
+	return name
+}
+
+// licenseFileSuffix returns the suffix of the LICENSE-<suffix> file holding
+// the text of a license of a multi-license project, e.g. APACHE for
+// Apache-2.0, or the whole SPDX identifier if several licenses would share it
+func licenseFileSuffix(name string, names []string) string {
+	short := func(name string) string {
+		id, _, _ := strings.Cut(SPDXID(name), "-")
+		return strings.ToUpper(id)
+	}
+	for _, other := range names {
+		if other != name && short(other) == short(name) {
+			return strings.ToUpper(SPDXID(name))
+		}
+	}
+	return short(name)
+}
@@ -0,0 +1,154 @@
+package licensed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// License texts missing from the bundled catalog are looked up in the
+// GitHub Licenses API first, whose texts carry the [year] and [fullname]
+// placeholders, then in the SPDX license list
+var (
+	githubLicensesURL = "https://api.github.com/licenses/"
+	spdxLicensesURL   = "https://spdx.org/licenses/"
+)
+
+// httpClient fetches license texts
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// errLicenseNotFound is returned by the license sources lacking a license
+var errLicenseNotFound = errors.New("license not found")
+
+// readLicense returns the full text of the license named by catalog name or
+// SPDX identifier from LicenseTextFile, LicenseDir, the bundled catalog, the
+// download cache or, unless Offline is set, the network
+func (p *Processor) readLicense(name string) ([]byte, error) {
+	_, text, err := p.readLicenseFile(name)
+	return text, err
+}
+
+// readLicenseHeader returns the text of the license for file headers: its
+// short notice if it has one, unless FullText is set, otherwise its full
+// text
+func (p *Processor) readLicenseHeader(name string) ([]byte, error) {
+	meta, text, err := p.readLicenseFile(name)
+	if err != nil || meta.Header == "" || p.opts.FullText {
+		return text, err
+	}
+	return []byte(meta.Header), nil
+}
+
+// readLicenseFile returns the metadata and the full text of the license
+// read by readLicense
+func (p *Processor) readLicenseFile(name string) (licenseMeta, []byte, error) {
+	// The license text file replaces the text of the configured license
+	if p.opts.LicenseTextFile != "" && strings.EqualFold(name, p.opts.License) {
+		content, err := os.ReadFile(p.opts.LicenseTextFile)
+		if err != nil {
+			return licenseMeta{}, nil, err
+		}
+		return splitLicenseMeta(content)
+	}
+
+	meta, text, err := readLicenseFile(name, p.opts.LicenseDir)
+	if errors.Is(err, fs.ErrNotExist) && catalogName(name) != name {
+		meta, text, err = readLicenseFile(catalogName(name), p.opts.LicenseDir)
+	}
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return meta, text, err
+	}
+	content, err := FetchLicense(name, p.opts.Offline)
+	if err != nil {
+		return licenseMeta{}, nil, p.unknownLicense(name, err)
+	}
+	return licenseMeta{}, content, nil
+}
+
+// FetchLicense returns the text of a license missing from the bundled
+// catalog, downloading it from the GitHub Licenses API or the SPDX license
+// list into the user cache directory on first use. With offline, only the
+// cache is consulted.
+func FetchLicense(name string, offline bool) ([]byte, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid license name %q", name)
+	}
+
+	// Serve the license from the cache if it was downloaded before
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	cachePath := filepath.Join(cacheDir, "licensed", strings.ToLower(name)+".txt")
+	if content, err := os.ReadFile(cachePath); err == nil {
+		return content, nil
+	}
+	if offline {
+		return nil, fmt.Errorf("license %s is not bundled or cached and downloads are disabled", name)
+	}
+
+	text, err := fetchGitHubLicense(name)
+	if errors.Is(err, errLicenseNotFound) {
+		text, err = fetchSPDXLicense(SPDXID(name))
+	}
+	if errors.Is(err, errLicenseNotFound) {
+		return nil, &UnknownLicenseError{Name: name}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("downloading license %s: %w", name, err)
+	}
+
+	// Cache the license for the next runs, which may be offline
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(cachePath, []byte(text), 0644); err != nil {
+		return nil, err
+	}
+	return []byte(text), nil
+}
+
+// fetchGitHubLicense downloads a license text from the GitHub Licenses API
+func fetchGitHubLicense(name string) (string, error) {
+	var license struct {
+		Body string `json:"body"`
+	}
+	if err := getJSON(githubLicensesURL+strings.ToLower(name), &license); err != nil {
+		return "", err
+	}
+	return license.Body, nil
+}
+
+// fetchSPDXLicense downloads a license text from the SPDX license list
+func fetchSPDXLicense(id string) (string, error) {
+	var license struct {
+		LicenseText string `json:"licenseText"`
+	}
+	if err := getJSON(spdxLicensesURL+id+".json", &license); err != nil {
+		return "", err
+	}
+	return license.LicenseText, nil
+}
+
+// getJSON decodes the JSON document at url into v
+func getJSON(url string, v any) error {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errLicenseNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
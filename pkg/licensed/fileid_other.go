@@ -0,0 +1,16 @@
+//go:build !unix
+
+package licensed
+
+import "os"
+
+// fileID identifies a file by device and inode
+type fileID struct {
+	dev, ino uint64
+}
+
+// inode is not available on systems without POSIX inodes, files are told
+// apart by their resolved path instead
+func inode(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
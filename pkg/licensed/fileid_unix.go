@@ -0,0 +1,23 @@
+//go:build unix
+
+package licensed
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID identifies a file by device and inode
+type fileID struct {
+	dev, ino uint64
+}
+
+// inode returns the identity of the file described by info, shared by all
+// hard and symbolic links to it
+func inode(info os.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
@@ -0,0 +1,37 @@
+package licensed
+
+import "fmt"
+
+// Stamp returns content with the license header added, without touching the
+// filesystem. filePath only selects the comment syntax, preamble rules and
+// path overrides; the file need not exist. Content carrying a different
+// header is returned unchanged unless Confirm replaces it.
+func (p *Processor) Stamp(content []byte, filePath string) ([]byte, error) {
+	commentSyntax, ok := p.commentSyntaxFor(filePath)
+	if !ok {
+		return nil, fmt.Errorf("unknown comment syntax for %s", filePath)
+	}
+
+	base, err := p.newHeaderRenderer(p.opts)
+	if err != nil {
+		return nil, err
+	}
+	renderer, err := p.rendererFor(map[int]*headerRenderer{-1: base}, filePath)
+	if err != nil {
+		return nil, err
+	}
+	license, err := renderer.render(filePath, p.opts.Year)
+	if err != nil {
+		return nil, err
+	}
+
+	newContent, _, err := p.insertHeader(string(content), filePath, license, commentSyntax)
+	return []byte(newContent), err
+}
+
+// Supports reports whether the comment syntax for filePath is known, i.e.
+// whether Stamp can add a header to it
+func (p *Processor) Supports(filePath string) bool {
+	_, ok := p.commentSyntaxFor(filePath)
+	return ok
+}
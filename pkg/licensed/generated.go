@@ -0,0 +1,72 @@
+package licensed
+
+import (
+	"regexp"
+	"strings"
+)
+
+// generatedMarker matches the line tools put in the files they generate,
+// Go's "Code generated by ... DO NOT EDIT.", once the comment delimiters
+// are stripped
+var generatedMarker = regexp.MustCompile(`^Code generated .* DO NOT EDIT\.$`)
+
+// generatedTag matches the @generated tag of tools such as Buck and Relay
+var generatedTag = regexp.MustCompile(`@generated\b`)
+
+// isGeneratedFile reports whether the comments leading the file carry a
+// generated code marker or .gitattributes marks it linguist-generated
+func (p *Processor) isGeneratedFile(filePath string) (bool, error) {
+	if p.gitattributes.has(filePath, "linguist-generated") {
+		return true, nil
+	}
+	buf, err := sniff(filePath)
+	if err != nil {
+		return false, err
+	}
+	text, _, err := decodeText(buf, true)
+	if err != nil {
+		return false, nil
+	}
+	commentSyntax, ok := p.commentSyntaxFor(filePath)
+	if !ok {
+		return false, nil
+	}
+	_, text = splitBOM(text)
+	_, rest := splitPreamble(text, filePath)
+	return hasGeneratedMarker(rest, commentSyntax), nil
+}
+
+// hasGeneratedMarker reports whether one of the comment blocks at the start
+// of content, before any code, is or contains a generated code marker. As
+// in Go, the marker must be a line of its own, so that files mentioning it
+// in code or in later comments are not taken for generated.
+func hasGeneratedMarker(content string, commentSyntax CommentSyntax) bool {
+	decoration := strings.TrimSpace(commentSyntax.BlockDecoration)
+	for {
+		content = strings.TrimLeft(content, " \t\r\n")
+		end := leadingCommentLength(content, commentSyntax)
+		if end == 0 {
+			return false
+		}
+		comment := content[:end]
+		content = content[end:]
+
+		if generatedTag.MatchString(comment) {
+			return true
+		}
+		for _, line := range strings.Split(comment, "\n") {
+			line = strings.TrimSpace(line)
+			for _, token := range []string{commentSyntax.LinePrefix, commentSyntax.BlockOpen, decoration} {
+				if token != "" {
+					line = strings.TrimPrefix(line, token)
+				}
+			}
+			if commentSyntax.BlockClose != "" {
+				line = strings.TrimSuffix(line, commentSyntax.BlockClose)
+			}
+			if generatedMarker.MatchString(strings.TrimSpace(line)) {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package licensed
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGeneratedFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]struct {
+		content   string
+		generated bool
+	}{
+		"gen.go": {
+			content:   "// Code generated by stringer; DO NOT EDIT.\n\npackage a\n",
+			generated: true,
+		},
+		"header_gen.go": {
+			content:   "// Copyright (c) 2024 x\n\n//go:build linux\n\n// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage a\n",
+			generated: true,
+		},
+		"gen.py": {
+			content:   "#!/usr/bin/env python\n# @generated by gen.py\nprint()\n",
+			generated: true,
+		},
+		"gen.css": {
+			content:   "/* Code generated by sass. DO NOT EDIT. */\na {}\n",
+			generated: true,
+		},
+		"code.go": {
+			content: "package a\n\nconst marker = \"// Code generated by x. DO NOT EDIT.\"\n",
+		},
+		"prose.go": {
+			content: "// Files starting with a Code generated ... DO NOT EDIT. line are\n// skipped.\npackage a\n",
+		},
+		"later.go": {
+			content: "package a\n\n// Code generated by stringer; DO NOT EDIT.\nfunc f() {}\n",
+		},
+		"tag.go": {
+			content: "package a\n\n// The @generated tag marks generated files\n",
+		},
+	}
+	for name, file := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(file.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := newTestProcessor(t, Options{Dir: dir}).Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, file := range files {
+		filePath := filepath.Join(dir, name)
+		skipped := slices.Contains(result.Skipped["generated"], filePath)
+		if skipped != file.generated {
+			t.Errorf("%s: skipped as generated %v, want %v", name, skipped, file.generated)
+		}
+		if !file.generated && !slices.Contains(result.Missing, filePath) {
+			t.Errorf("%s: not checked, result %+v", name, result)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package licensed
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stagedFiles lists the files under dir that are staged for commit
+func stagedFiles(dir string) ([]string, error) {
+	return diffFiles(dir, "--cached")
+}
+
+// changedFiles lists the files under dir that were added or modified since
+// the merge base of ref and HEAD, including uncommitted changes
+func changedFiles(dir, ref string) ([]string, error) {
+	return diffFiles(dir, "--merge-base", ref)
+}
+
+// diffFiles lists the added, copied, modified and renamed files under dir
+// reported by git diff with args
+func diffFiles(dir string, args ...string) ([]string, error) {
+	args = append([]string{"-C", dir, "diff", "--name-only", "--diff-filter=ACMR", "--relative"}, args...)
+	output, err := exec.Command("git", args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return nil, errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		files = append(files, filepath.Join(dir, filepath.FromSlash(line)))
+	}
+	return files, nil
+}
+
+// trackedFiles lists the files under dir tracked by git, failing if dir is
+// not in a git work tree
+func trackedFiles(dir string) ([]string, error) {
+	output, err := exec.Command("git", "-C", dir, "ls-files", "-z").Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return nil, errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, name := range strings.Split(string(output), "\x00") {
+		if name != "" {
+			files = append(files, filepath.Join(dir, filepath.FromSlash(name)))
+		}
+	}
+	return files, nil
+}
+
+// gitYears returns the year range, or single year, between the first and
+// last commits that touched filePath. It returns an empty string if the file
+// has no history.
+func gitYears(filePath string) (string, error) {
+	dir, name := filepath.Split(filePath)
+	if dir == "" {
+		dir = "."
+	}
+	output, err := exec.Command("git", "-C", dir, "log", "--follow", "--format=%ad", "--date=format:%Y", "--", name).Output()
+	if err != nil {
+		return "", err
+	}
+
+	years := strings.Fields(string(output))
+	if len(years) == 0 {
+		return "", nil
+	}
+	first, last := years[len(years)-1], years[0]
+	if first == last {
+		return first, nil
+	}
+	return first + "-" + last, nil
+}
+
+// gitYearsOr returns the git year range of filePath, falling back to
+// fallback, or the current year if that is empty, for files without history
+func gitYearsOr(filePath, fallback string) string {
+	years, err := gitYears(filePath)
+	if err == nil && years != "" {
+		return years
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return strconv.Itoa(time.Now().Year())
+}
+
+// GitUserName returns the user.name configured for the repository at dir
+func GitUserName(dir string) string {
+	output, err := exec.Command("git", "-C", dir, "config", "user.name").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,117 @@
+package licensed
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// skipAttributes are the .gitattributes attributes marking files that are
+// not the project's own source, with the reason they are skipped for
+var skipAttributes = []struct {
+	name   string
+	reason string
+}{
+	{"linguist-vendored", "vendored"},
+	{"export-ignore", "export-ignore"},
+}
+
+// gitattributesTree holds the rules of the attributes licensed looks at, as
+// set by the .gitattributes file of each directory, keyed by the directory's
+// slash-separated path relative to the root and then by attribute.
+// Directories are read the first time a file below them is looked up.
+type gitattributesTree struct {
+	root  string
+	rules map[string]map[string][]ignoreRule
+}
+
+func newGitattributesTree(root string) *gitattributesTree {
+	return &gitattributesTree{root: root, rules: make(map[string]map[string][]ignoreRule)}
+}
+
+// has reports whether the .gitattributes files of the ancestor directories
+// of filePath set the attribute for it, with deeper files taking precedence
+func (t *gitattributesTree) has(filePath, attr string) bool {
+	rel, ok := slashRel(t.root, filePath)
+	if !ok {
+		return false
+	}
+
+	var set bool
+	dir := "."
+	for {
+		rules, ok := t.rules[dir]
+		if !ok {
+			data, _ := os.ReadFile(filepath.Join(t.root, filepath.FromSlash(dir), ".gitattributes"))
+			rules = parseAttributes(data)
+			t.rules[dir] = rules
+		}
+		sub := rel
+		if dir != "." {
+			sub = strings.TrimPrefix(rel, dir+"/")
+		}
+		if result, matched := matchIgnoreRules(rules[attr], sub, false); matched {
+			set = result
+		}
+
+		// Descend one directory towards the file
+		i := strings.IndexByte(sub, '/')
+		if i < 0 {
+			return set
+		}
+		dir = path.Join(dir, sub[:i])
+	}
+}
+
+// skipReason returns why .gitattributes rules out filePath, as vendored or
+// export-ignore, or "" if it does not
+func (t *gitattributesTree) skipReason(filePath string) string {
+	for _, attr := range skipAttributes {
+		if t.has(filePath, attr.name) {
+			return attr.reason
+		}
+	}
+	return ""
+}
+
+// parseAttributes returns the patterns of a .gitattributes file setting or
+// unsetting linguist-generated, linguist-vendored or export-ignore by
+// attribute, as rules whose negate flag marks files the attribute is unset
+// for
+func parseAttributes(data []byte) map[string][]ignoreRule {
+	rules := make(map[string][]ignoreRule)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		// The last mention of an attribute on the line wins
+		values := make(map[string]bool)
+		for _, attr := range fields[1:] {
+			name, value, hasValue := strings.Cut(attr, "=")
+			set := true
+			switch {
+			case strings.HasPrefix(name, "-"), strings.HasPrefix(name, "!"):
+				name, set = name[1:], false
+			case hasValue:
+				set = value != "false"
+			}
+			switch name {
+			case "linguist-generated", "linguist-vendored", "export-ignore":
+				values[name] = set
+			}
+		}
+
+		// Patterns follow the .gitignore syntax, except for negation
+		for name, set := range values {
+			parsed := parseIgnoreRules([]byte(strings.TrimPrefix(fields[0], "!")))
+			if len(parsed) == 1 {
+				parsed[0].negate = !set
+				rules[name] = append(rules[name], parsed[0])
+			}
+		}
+	}
+	return rules
+}
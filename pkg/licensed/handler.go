@@ -0,0 +1,173 @@
+package licensed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Actions a FileHandler command is run for
+const (
+	HandlerAdd    = "add"
+	HandlerCheck  = "check"
+	HandlerRemove = "remove"
+)
+
+// FileHandler hands the files matching Paths over to an external command,
+// for formats licensed cannot comment, e.g. to store the license in the
+// metadata of .docx or .svg files. Command is run by the shell, sh or cmd
+// on Windows, with the action, add, check or remove, and the path of the
+// file appended as arguments, from the project directory. The header
+// rendered for the file is passed on stdin as plain text, along with
+// LICENSED_LICENSE, LICENSED_OWNER and LICENSED_YEAR in the environment, and
+// the action and path again as LICENSED_ACTION and LICENSED_FILE.
+//
+// The command exits with 0 on success. For check, 0 means the file carries
+// the header and 1 that it lacks it. Any other exit status is an error of
+// the file, reported with what the command wrote to stderr. Whether add and
+// remove changed the file is told by its content; in a dry run, check is
+// run instead to tell whether they would.
+type FileHandler struct {
+	// Paths are gitignore-style patterns of the files handled, relative
+	// to the project directory
+	Paths []string `yaml:"paths" toml:"paths"`
+	// Command is the shell command line run for each file
+	Command string `yaml:"command" toml:"command"`
+}
+
+// fileHandler is a FileHandler with its paths parsed
+type fileHandler struct {
+	FileHandler
+	rules []ignoreRule
+}
+
+func parseHandlers(handlers []FileHandler) []fileHandler {
+	parsed := make([]fileHandler, len(handlers))
+	for i, handler := range handlers {
+		parsed[i] = fileHandler{FileHandler: handler, rules: parseIgnoreRules([]byte(strings.Join(handler.Paths, "\n")))}
+	}
+	return parsed
+}
+
+// handlerFor returns the first handler whose paths match filePath, or nil
+func (p *Processor) handlerFor(filePath string) *fileHandler {
+	rel := p.relPath(filePath)
+	for i := range p.handlers {
+		if matchPath(p.handlers[i].rules, rel, false) {
+			return &p.handlers[i]
+		}
+	}
+	return nil
+}
+
+// run runs the command of the handler for action on filePath from the
+// project directory, passing header on stdin, and returns its exit status
+func (h *fileHandler) run(action, filePath, header string, opts Options) (int, error) {
+	filePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return -1, err
+	}
+	cmd := shellCommand(h.Command)
+	cmd.Dir = opts.Dir
+	cmd.Stdin = strings.NewReader(header)
+	cmd.Env = append(os.Environ(),
+		"LICENSED_ACTION="+action,
+		"LICENSED_FILE="+filePath,
+		"LICENSED_LICENSE="+SPDXID(opts.License),
+		"LICENSED_OWNER="+ownerNames(opts.Owners),
+		"LICENSED_YEAR="+opts.Year,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.As(err, &exitErr):
+		if action == HandlerCheck && exitErr.ExitCode() == 1 {
+			return 1, nil
+		}
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return exitErr.ExitCode(), fmt.Errorf("handler %s %s: %s", h.Command, action, message)
+	default:
+		return -1, fmt.Errorf("handler %s: %w", h.Command, err)
+	}
+}
+
+// handleFile adds the header to filePath through handler, or checks for it
+// with checkOnly, recording the outcome in result
+func (p *Processor) handleFile(result *Result, handler *fileHandler, filePath, licenseContent string, checkOnly bool) error {
+	opts, _ := p.optionsFor(filePath)
+	status, err := handler.run(HandlerCheck, filePath, licenseContent, opts)
+	if err != nil {
+		return err
+	}
+	switch {
+	case status == 0:
+		result.Licensed = append(result.Licensed, filePath)
+	case checkOnly:
+		result.Missing = append(result.Missing, filePath)
+		p.log.Warn("missing license header", "path", filePath)
+	case p.opts.DryRun:
+		result.Changed = append(result.Changed, filePath)
+	default:
+		changed, err := p.changeWithHandler(filePath, func() error {
+			_, err := handler.run(HandlerAdd, filePath, licenseContent, opts)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if changed {
+			result.Changed = append(result.Changed, filePath)
+		} else {
+			result.Licensed = append(result.Licensed, filePath)
+		}
+	}
+	return nil
+}
+
+// removeWithHandler removes the header of filePath through handler,
+// reporting whether the file was, or in a dry run would be, changed
+func (p *Processor) removeWithHandler(handler *fileHandler, filePath, licenseContent string) (bool, error) {
+	opts, _ := p.optionsFor(filePath)
+	if p.opts.DryRun {
+		status, err := handler.run(HandlerCheck, filePath, licenseContent, opts)
+		return status == 0, err
+	}
+	return p.changeWithHandler(filePath, func() error {
+		_, err := handler.run(HandlerRemove, filePath, licenseContent, opts)
+		return err
+	})
+}
+
+// changeWithHandler backs filePath up with Backup and runs a handler on it,
+// reporting whether that changed its content
+func (p *Processor) changeWithHandler(filePath string, run func() error) (bool, error) {
+	before, err := hashFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	if p.opts.Backup {
+		if err := p.backupFile(filePath); err != nil {
+			return false, fmt.Errorf("backing up %s: %w", filePath, err)
+		}
+	}
+	if err := run(); err != nil {
+		return false, err
+	}
+	after, err := hashFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	return before != after, nil
+}
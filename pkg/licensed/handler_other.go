@@ -0,0 +1,11 @@
+//go:build !windows
+
+package licensed
+
+import "os/exec"
+
+// shellCommand returns command run by sh with the action and path of the
+// file appended from LICENSED_ACTION and LICENSED_FILE
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("sh", "-c", command+` "$LICENSED_ACTION" "$LICENSED_FILE"`)
+}
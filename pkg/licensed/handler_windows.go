@@ -0,0 +1,21 @@
+//go:build windows
+
+package licensed
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand returns command run by cmd with the action and path of the
+// file appended from LICENSED_ACTION and LICENSED_FILE. Delayed expansion
+// substitutes them after cmd parsed the line, so that &, % or ^ in the path
+// are taken literally.
+func shellCommand(command string) *exec.Cmd {
+	cmd := exec.Command("cmd")
+	// Go would quote the line the way the C runtime parses it, not cmd
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine: `cmd /V:ON /S /C "` + command + ` !LICENSED_ACTION! "!LICENSED_FILE!""`,
+	}
+	return cmd
+}
@@ -0,0 +1,141 @@
+package licensed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// hookMarker identifies pre-commit hooks written by licensed
+const hookMarker = "# Installed by licensed"
+
+// PreCommitConfigFile is the configuration file of the pre-commit framework
+const PreCommitConfigFile = ".pre-commit-config.yaml"
+
+// ErrHookExists is returned when a pre-commit hook not written by licensed
+// is in the way
+var ErrHookExists = errors.New("a pre-commit hook not installed by licensed exists")
+
+// InstallPreCommitHook writes a pre-commit hook into the git repository
+// containing dir that runs the shell command line. An existing hook is only
+// replaced if it was installed by licensed or force is set.
+func InstallPreCommitHook(dir, command string, force bool) error {
+	output, err := exec.Command("git", "-C", dir, "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return fmt.Errorf("not a git repository: %s", dir)
+	}
+	hooksDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(hooksDir) {
+		hooksDir = filepath.Join(dir, hooksDir)
+	}
+	hookPath := filepath.Join(hooksDir, "pre-commit")
+
+	// Refuse to overwrite a hook that licensed did not write
+	existing, err := os.ReadFile(hookPath)
+	if err == nil && !strings.Contains(string(existing), hookMarker) && !force {
+		return fmt.Errorf("%s: %w", hookPath, ErrHookExists)
+	}
+
+	script := "#!/bin/sh\n" + hookMarker + "\n" + command + "\n"
+
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(hookPath, []byte(script), 0755)
+}
+
+// ShellQuote quotes s for use as a single POSIX shell word
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// AddPreCommitConfig adds a local hook running the shell command line to the
+// .pre-commit-config.yaml file in dir, creating the file if needed. The file
+// is left alone if it already has a licensed hook, reporting false.
+func AddPreCommitConfig(dir, command string) (bool, error) {
+	path := filepath.Join(dir, PreCommitConfigFile)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	// Parse the existing configuration, keeping its comments and layout
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("parsing %s: not a mapping", path)
+	}
+
+	// Find or create the list of hook repositories
+	var repos *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "repos" {
+			repos = root.Content[i+1]
+		}
+	}
+	if repos == nil {
+		repos = &yaml.Node{Kind: yaml.SequenceNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "repos"}, repos)
+	}
+	if repos.Kind != yaml.SequenceNode {
+		return false, fmt.Errorf("parsing %s: repos is not a list", path)
+	}
+
+	// Leave the file alone if a licensed hook is configured already
+	var existing struct {
+		Repos []struct {
+			Hooks []struct {
+				ID string `yaml:"id"`
+			} `yaml:"hooks"`
+		} `yaml:"repos"`
+	}
+	if err := root.Decode(&existing); err == nil {
+		for _, repo := range existing.Repos {
+			for _, hook := range repo.Hooks {
+				if hook.ID == "licensed" {
+					return false, nil
+				}
+			}
+		}
+	}
+
+	type hook struct {
+		ID            string `yaml:"id"`
+		Name          string `yaml:"name"`
+		Entry         string `yaml:"entry"`
+		Language      string `yaml:"language"`
+		PassFilenames bool   `yaml:"pass_filenames"`
+	}
+	var entry yaml.Node
+	err = entry.Encode(struct {
+		Repo  string `yaml:"repo"`
+		Hooks []hook `yaml:"hooks"`
+	}{
+		Repo:  "local",
+		Hooks: []hook{{ID: "licensed", Name: "licensed", Entry: command, Language: "system"}},
+	})
+	if err != nil {
+		return false, err
+	}
+	repos.Content = append(repos.Content, &entry)
+
+	var output bytes.Buffer
+	encoder := yaml.NewEncoder(&output)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return false, err
+	}
+	return true, AtomicWriteFile(path, output.Bytes(), 0644)
+}
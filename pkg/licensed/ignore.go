@@ -0,0 +1,420 @@
+package licensed
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// metadataDirs are directories that never hold files to license: version
+// control metadata and backups
+var metadataDirs = map[string]bool{
+	BackupDir: true,
+	".git":    true,
+	".hg":     true,
+	".svn":    true,
+	".bzr":    true,
+}
+
+// dependencyDirs hold dependencies wherever they are, and buildOutputDirs
+// build output and vendored code at the top of the project, names that
+// deeper down may well be source packages such as pkg/build. Both are
+// skipped unless NoDefaultIgnores is set.
+var (
+	dependencyDirs  = map[string]bool{"node_modules": true}
+	buildOutputDirs = map[string]bool{"vendor": true, "dist": true, "build": true, "target": true}
+)
+
+// isDefaultSkippedDir reports whether the directory at rel, slash-separated
+// and relative to the project directory, is skipped regardless of ignore
+// files
+func (p *Processor) isDefaultSkippedDir(rel string) bool {
+	name := path.Base(rel)
+	switch {
+	case metadataDirs[name]:
+		return true
+	case p.opts.NoDefaultIgnores:
+		return false
+	}
+	return dependencyDirs[name] || (buildOutputDirs[name] && !strings.Contains(rel, "/"))
+}
+
+// ignoreRule is a single gitignore-style pattern
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// prefix is the literal leading directories of an anchored pattern
+	prefix string
+}
+
+// parseIgnoreRules parses gitignore-style patterns, skipping blank lines,
+// comments and invalid patterns
+func parseIgnoreRules(data []byte) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(string(data), "\n") {
+		if rule, ok, err := parseIgnoreRule(line); ok && err == nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parsePatterns is parseIgnoreRules, logging the invalid patterns found in
+// source
+func (p *Processor) parsePatterns(data []byte, source string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(string(data), "\n") {
+		rule, ok, err := parseIgnoreRule(line)
+		if err != nil {
+			p.log.Warn("pattern error", "source", source, "pattern", strings.TrimSpace(line), "error", err)
+			continue
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parseIgnoreRule parses a gitignore-style pattern, reporting false for a
+// blank line or a comment
+func parseIgnoreRule(line string) (ignoreRule, bool, error) {
+	var rule ignoreRule
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false, nil
+	}
+
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	if filepath.Separator == '\\' {
+		line = slashPattern(line)
+	}
+	if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// Patterns without a slash match at any depth
+	if !strings.Contains(line, "/") {
+		line = "**/" + line
+	}
+	line = strings.TrimPrefix(line, "/")
+	if literal := line[:strings.IndexAny(line+"*", "*?[\\")]; strings.Contains(literal, "/") {
+		rule.prefix = literal[:strings.LastIndex(literal, "/")]
+	}
+
+	re, err := globToRegexp(line)
+	if err != nil {
+		return rule, false, err
+	}
+	rule.re = re
+	return rule, true, nil
+}
+
+// slashPattern turns the backslash separators of a pattern written for
+// Windows, such as build\*, into slashes. Only a leading backslash escaping
+// # or ! is kept.
+func slashPattern(pattern string) string {
+	if strings.HasPrefix(pattern, `\#`) || strings.HasPrefix(pattern, `\!`) {
+		return pattern[:2] + strings.ReplaceAll(pattern[2:], `\`, "/")
+	}
+	return strings.ReplaceAll(pattern, `\`, "/")
+}
+
+// anchorPatterns rewrites the patterns given as absolute paths inside dir,
+// such as C:\project\build\, into patterns anchored at dir
+func anchorPatterns(dir string, patterns []string) []string {
+	anchored := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		anchored[i] = pattern
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		if !filepath.IsAbs(pattern) {
+			continue
+		}
+		rel, ok := slashRel(dir, pattern)
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(pattern, "/") || strings.HasSuffix(pattern, string(filepath.Separator)) {
+			rel += "/"
+		}
+		if negate {
+			rel = "!/" + rel
+		} else {
+			rel = "/" + rel
+		}
+		anchored[i] = rel
+	}
+	return anchored
+}
+
+// globToRegexp converts a gitignore glob into a regular expression matching
+// slash-separated paths. A match also covers everything below the path.
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// matchIgnoreRules reports whether relPath, relative to the directory the
+// rules were read from, is ignored. Later rules override earlier ones.
+func matchIgnoreRules(rules []ignoreRule, relPath string, isDir bool) (ignored, matched bool) {
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(relPath) {
+			ignored, matched = !rule.negate, true
+		}
+	}
+	return ignored, matched
+}
+
+// shouldIgnoreFile reports whether the .licensed-ignore patterns exclude
+// filePath or one of its parent directories
+func (p *Processor) shouldIgnoreFile(filePath string) bool {
+	return p.shouldIgnorePath(filePath, false)
+}
+
+// shouldIgnorePath matches filePath, relative to the project directory,
+// against the .licensed-ignore patterns, those of the .licensed-ignore files
+// in subdirectories applying relative to their directory and taking
+// precedence. A path inside an ignored directory is ignored too, as with
+// .gitignore, unless a negated pattern re-includes it.
+func (p *Processor) shouldIgnorePath(filePath string, isDir bool) bool {
+	rel := p.relPath(filePath)
+	ignored := matchPath(p.ignoreRules, rel, isDir)
+	if _, ok := slashRel(p.opts.Dir, filePath); ok {
+		if result, matched := p.nestedIgnores.match(rel, isDir); matched {
+			ignored = result
+		}
+	}
+	return ignored
+}
+
+// shouldSkipDir reports whether the walk can skip the directory at
+// filePath, which is ignored without a negated pattern re-including files
+// below it
+func (p *Processor) shouldSkipDir(filePath string) bool {
+	rel := p.relPath(filePath)
+	return p.shouldIgnorePath(filePath, true) && !mayReinclude(p.ignoreRules, rel) && !p.nestedIgnores.mayReinclude(rel)
+}
+
+// isHidden reports whether filePath is a dotfile or lies in a dot-directory
+// of the project
+func (p *Processor) isHidden(filePath string) bool {
+	rel, ok := slashRel(p.opts.Dir, filePath)
+	if !ok {
+		rel = filepath.ToSlash(filepath.Clean(filePath))
+	}
+	for _, name := range strings.Split(rel, "/") {
+		if isHiddenName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHiddenName reports whether a file or directory name starts with a dot,
+// other than . and ..
+func isHiddenName(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// isIncluded reports whether filePath matches the IncludePatterns, which
+// include every file if there are none
+func (p *Processor) isIncluded(filePath string) bool {
+	return len(p.includeRules) == 0 || matchPath(p.includeRules, p.relPath(filePath), false)
+}
+
+// relPath returns filePath relative to the project directory, with slashes
+func (p *Processor) relPath(filePath string) string {
+	if rel, ok := slashRel(p.opts.Dir, filePath); ok {
+		return rel
+	}
+	return filepath.ToSlash(filePath)
+}
+
+// slashRel returns filePath relative to root with slashes, reporting false
+// if it lies outside root. Relative and absolute paths can be mixed, and
+// drive letters compare case-insensitively.
+func slashRel(root, filePath string) (string, bool) {
+	if filepath.IsAbs(root) != filepath.IsAbs(filePath) {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return "", false
+		}
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return "", false
+		}
+		root, filePath = absRoot, absPath
+	}
+	if volume := filepath.VolumeName(root); volume != "" && strings.EqualFold(volume, filepath.VolumeName(filePath)) {
+		filePath = volume + filePath[len(volume):]
+	}
+
+	rel, err := filepath.Rel(root, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// matchPath reports whether rules match the slash-separated rel path or one
+// of its parent directories. The deepest match wins, so a negated pattern
+// such as !third_party/ours/** re-includes files of an ignored directory.
+func matchPath(rules []ignoreRule, rel string, isDir bool) bool {
+	ignored, _ := matchPathRules(rules, rel, isDir)
+	return ignored
+}
+
+// matchPathRules is matchPath, also reporting whether any rule matched
+func matchPathRules(rules []ignoreRule, rel string, isDir bool) (ignored, matched bool) {
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if result, ok := matchIgnoreRules(rules, strings.Join(parts[:i], "/"), true); ok {
+			ignored, matched = result, true
+		}
+	}
+	if result, ok := matchIgnoreRules(rules, rel, isDir); ok {
+		ignored, matched = result, true
+	}
+	return ignored, matched
+}
+
+// mayReinclude reports whether a negated pattern naming a path below the
+// slash-separated directory rel may re-include some of its files, in which
+// case the directory is walked even though it is ignored. Negated patterns
+// matching at any depth, such as !*.go, do not count, as with .gitignore.
+func mayReinclude(rules []ignoreRule, rel string) bool {
+	for _, rule := range rules {
+		if rule.negate && rule.prefix != "" && (strings.HasPrefix(rule.prefix, rel+"/") || strings.HasPrefix(rel+"/", rule.prefix+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreTree holds the rules of the ignore files named name in each
+// directory below root, such as .gitignore, keyed by the directory's
+// slash-separated path relative to the root. Directories are read the first
+// time a path below them is looked up.
+type ignoreTree struct {
+	root  string
+	name  string
+	rules map[string][]ignoreRule
+}
+
+func newIgnoreTree(root, name string) *ignoreTree {
+	return &ignoreTree{root: root, name: name, rules: make(map[string][]ignoreRule)}
+}
+
+// rulesIn returns the rules of the ignore file of the slash-separated
+// directory dir, reading it on first use
+func (t *ignoreTree) rulesIn(dir string) []ignoreRule {
+	rules, ok := t.rules[dir]
+	if !ok {
+		data, _ := os.ReadFile(filepath.Join(t.root, filepath.FromSlash(dir), t.name))
+		rules = parseIgnoreRules(data)
+		t.rules[dir] = rules
+	}
+	return rules
+}
+
+// ignored reports whether filePath is ignored by the ignore files of its
+// ancestor directories
+func (t *ignoreTree) ignored(filePath string, isDir bool) bool {
+	rel, ok := slashRel(t.root, filePath)
+	if !ok {
+		return false
+	}
+	ignored, _ := t.match(rel, isDir)
+	return ignored
+}
+
+// match matches the slash-separated rel path against the ignore files of
+// its ancestor directories, each applying relative to its directory and
+// deeper files taking precedence, and reports whether any rule matched
+func (t *ignoreTree) match(rel string, isDir bool) (ignored, matched bool) {
+	dir := "."
+	sub := rel
+	for {
+		if result, ok := matchPathRules(t.rulesIn(dir), sub, isDir); ok {
+			ignored, matched = result, true
+		}
+
+		// Descend one directory towards the path
+		i := strings.IndexByte(sub, '/')
+		if i < 0 {
+			return ignored, matched
+		}
+		dir = path.Join(dir, sub[:i])
+		sub = sub[i+1:]
+	}
+}
+
+// mayReinclude reports whether a negated pattern in the ignore files of the
+// ancestor directories of the slash-separated directory rel may re-include
+// some of its files
+func (t *ignoreTree) mayReinclude(rel string) bool {
+	dir := "."
+	sub := rel
+	for {
+		if mayReinclude(t.rulesIn(dir), sub) {
+			return true
+		}
+		i := strings.IndexByte(sub, '/')
+		if i < 0 {
+			return false
+		}
+		dir = path.Join(dir, sub[:i])
+		sub = sub[i+1:]
+	}
+}
@@ -0,0 +1,558 @@
+package licensed
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed licenses/*.txt
+var embeddedLicenses embed.FS
+
+// licensedIgnoreFile holds the default .licensed-ignore patterns
+//
+//go:embed .licensed-ignore
+var licensedIgnoreFile []byte
+
+// LicenseInfo describes a license of the catalog
+type LicenseInfo struct {
+	// Name is the name selecting the license, e.g. "apache-2.0"
+	Name string `json:"name"`
+	// SPDXID is the SPDX identifier of the license, e.g. "Apache-2.0"
+	SPDXID string `json:"spdx_id"`
+	// Title is the full name of the license
+	Title string `json:"title"`
+	// Description summarizes the license in one line
+	Description string `json:"description"`
+	// Notice is set for licenses whose NOTICE file, if any, must be passed
+	// on with the work
+	Notice bool `json:"notice,omitempty"`
+	// ShortHeader is set for licenses with a short notice used for file
+	// headers instead of the full text
+	ShortHeader bool `json:"short_header,omitempty"`
+}
+
+// catalog describes the bundled licenses by name, from the metadata blocks
+// of their texts
+var catalog = loadCatalog()
+
+// licenseMeta is the metadata block heading a license text between ---
+// lines, e.g.
+//
+//	---
+//	spdx-id: Apache-2.0
+//	title: Apache License 2.0
+//	header: |
+//	  Copyright [year] [fullname]
+//	  ...
+//	---
+//
+// Header is the short notice used for file headers instead of the full
+// text, such as the standard Apache-2.0 notice.
+type licenseMeta struct {
+	SPDXID      string `yaml:"spdx-id"`
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Notice      bool   `yaml:"notice"`
+	Header      string `yaml:"header"`
+}
+
+// splitLicenseMeta separates the metadata block of a license text, if any,
+// from the text
+func splitLicenseMeta(content []byte) (licenseMeta, []byte, error) {
+	var meta licenseMeta
+	text := bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(text, []byte("---\n")) {
+		return meta, content, nil
+	}
+	block, body, ok := bytes.Cut(text[len("---\n"):], []byte("\n---\n"))
+	if !ok {
+		return meta, content, nil
+	}
+	if err := yaml.Unmarshal(block, &meta); err != nil {
+		return meta, nil, fmt.Errorf("parsing license metadata: %w", err)
+	}
+	return meta, body, nil
+}
+
+// loadCatalog reads the metadata blocks of the bundled licenses
+func loadCatalog() map[string]LicenseInfo {
+	files, err := fs.ReadDir(embeddedLicenses, "licenses")
+	if err != nil {
+		panic(err)
+	}
+	infos := make(map[string]LicenseInfo)
+	for _, file := range files {
+		content, err := fs.ReadFile(embeddedLicenses, "licenses/"+file.Name())
+		if err != nil {
+			panic(err)
+		}
+		meta, _, err := splitLicenseMeta(content)
+		if err != nil {
+			panic(fmt.Errorf("%s: %w", file.Name(), err))
+		}
+		name := strings.TrimSuffix(file.Name(), ".txt")
+		infos[name] = meta.info(name)
+	}
+	return infos
+}
+
+// info describes the license of the given name by its metadata
+func (meta licenseMeta) info(name string) LicenseInfo {
+	return LicenseInfo{
+		Name:        name,
+		SPDXID:      meta.SPDXID,
+		Title:       meta.Title,
+		Description: meta.Description,
+		Notice:      meta.Notice,
+		ShortHeader: meta.Header != "",
+	}
+}
+
+// SPDXID returns the SPDX identifier of a license name, falling back to the
+// name itself for licenses outside the bundled catalog. The licenses of an
+// SPDX license expression are converted one by one.
+func SPDXID(name string) string {
+	tokens, err := tokenizeLicense(name)
+	if err != nil || len(tokens) <= 1 {
+		if info, ok := catalog[catalogName(name)]; ok {
+			return info.SPDXID
+		}
+		return name
+	}
+
+	var b strings.Builder
+	for i, token := range tokens {
+		if i > 0 && token.text != ")" && tokens[i-1].text != "(" {
+			b.WriteString(" ")
+		}
+		if token.license {
+			b.WriteString(SPDXID(token.text))
+		} else {
+			b.WriteString(token.text)
+		}
+	}
+	return b.String()
+}
+
+// ReadLicense returns the full text of the named license, preferring a
+// custom text in licenseDir over the embedded catalog
+func ReadLicense(name, licenseDir string) ([]byte, error) {
+	_, text, err := readLicenseFile(name, licenseDir)
+	return text, err
+}
+
+// ReadLicenseHeader returns the short notice of the named license for file
+// headers, or its full text if it has none
+func ReadLicenseHeader(name, licenseDir string) ([]byte, error) {
+	meta, text, err := readLicenseFile(name, licenseDir)
+	if err != nil || meta.Header == "" {
+		return text, err
+	}
+	return []byte(meta.Header), nil
+}
+
+// readLicenseFile returns the metadata and the text of the named license,
+// preferring a custom text in licenseDir over the embedded catalog
+func readLicenseFile(name, licenseDir string) (licenseMeta, []byte, error) {
+	var content []byte
+	var err error
+	if licenseDir != "" {
+		content, err = os.ReadFile(filepath.Join(licenseDir, name+".txt"))
+		if err != nil && !os.IsNotExist(err) {
+			return licenseMeta{}, nil, err
+		}
+	}
+	if content == nil {
+		if content, err = fs.ReadFile(embeddedLicenses, "licenses/"+name+".txt"); err != nil {
+			return licenseMeta{}, nil, err
+		}
+	}
+	return splitLicenseMeta(content)
+}
+
+// Owner is a copyright holder, with the years of its copyright if they
+// differ from the year of the run
+type Owner struct {
+	Name  string `yaml:"name" toml:"name"`
+	Year  string `yaml:"year" toml:"year"`
+	Email string `yaml:"email,omitempty" toml:"email,omitempty"`
+}
+
+// ErrUnresolvedPlaceholder is returned for a license text or header naming a
+// placeholder that has no value, such as [email] without an owner email
+var ErrUnresolvedPlaceholder = errors.New("unresolved placeholder")
+
+// Placeholders are the values filled in for the placeholders of license
+// texts: [year], [fullname], [email], [project], [organization] and [url]
+type Placeholders struct {
+	Owners       []Owner
+	Year         string
+	Project      string
+	Organization string
+	URL          string
+}
+
+// placeholderSettings names the setting providing each placeholder
+var placeholderSettings = map[string]string{
+	"[year]":         "year",
+	"[fullname]":     "copyright holder",
+	"[email]":        "owner email",
+	"[project]":      "project name",
+	"[organization]": "organization",
+	"[url]":          "project URL",
+}
+
+// Fill fills in the placeholders of a license text. Lines naming [fullname]
+// or [email] are repeated for every owner, with the owner's own year, if
+// any, filled in for [year]. Every placeholder the text names is required:
+// one without a value is an ErrUnresolvedPlaceholder.
+func (v Placeholders) Fill(text string) (string, error) {
+	filled, missing := v.fill(text)
+	if missing != "" {
+		return "", fmt.Errorf("%w %s: no %s given", ErrUnresolvedPlaceholder, missing, placeholderSettings[missing])
+	}
+	return filled, nil
+}
+
+// fill is Fill, returning the first placeholder without a value instead of
+// an error
+func (v Placeholders) fill(text string) (string, string) {
+	owners := v.Owners
+	if len(owners) == 0 {
+		owners = []Owner{{}}
+	}
+
+	var missing string
+	replace := func(line string, values ...string) string {
+		for i := 0; i < len(values); i += 2 {
+			if missing == "" && values[i+1] == "" && strings.Contains(line, values[i]) {
+				missing = values[i]
+			}
+		}
+		return strings.NewReplacer(values...).Replace(line)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = replace(line, "[project]", v.Project, "[organization]", v.Organization, "[url]", v.URL)
+		if !strings.Contains(line, "[fullname]") && !strings.Contains(line, "[email]") {
+			lines = append(lines, replace(line, "[year]", v.Year))
+			continue
+		}
+		for _, owner := range owners {
+			ownerYear := owner.Year
+			if ownerYear == "" {
+				ownerYear = v.Year
+			}
+			lines = append(lines, replace(line, "[year]", ownerYear, "[fullname]", owner.Name, "[email]", owner.Email))
+		}
+	}
+	return strings.Join(lines, "\n"), missing
+}
+
+// FillPlaceholders fills in the [fullname] and [year] placeholders of a
+// license text like Placeholders.Fill, leaving those without a value empty
+func FillPlaceholders(text string, owners []Owner, year string) string {
+	filled, _ := Placeholders{Owners: owners, Year: year}.fill(text)
+	return filled
+}
+
+// placeholders returns the placeholder values of the run
+func (p *Processor) placeholders() Placeholders {
+	return Placeholders{
+		Owners:       p.opts.Owners,
+		Year:         p.opts.Year,
+		Project:      p.projectName(),
+		Organization: p.opts.Organization,
+		URL:          p.opts.URL,
+	}
+}
+
+// projectName returns Project, defaulting to the name of the project
+// directory
+func (p *Processor) projectName() string {
+	if p.opts.Project != "" {
+		return p.opts.Project
+	}
+	dir, err := filepath.Abs(p.opts.Dir)
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(dir)
+}
+
+// LicenseText returns the text of the selected license with the owner and
+// year filled in. The texts of the licenses of an SPDX license expression
+// follow each other.
+func (p *Processor) LicenseText() (string, error) {
+	content, err := p.expressionText(p.opts.License)
+	if err != nil {
+		return "", err
+	}
+	return p.placeholders().Fill(content)
+}
+
+// WriteLicenseFiles writes the license text with the owner and year filled
+// in to path, or a diff of the change in a dry run. For an SPDX license
+// expression naming several licenses, each text goes to its own
+// path-<suffix> file instead, e.g. LICENSE-MIT and LICENSE-APACHE. It returns
+// the files written.
+func (p *Processor) WriteLicenseFiles(path string) ([]string, error) {
+	names, err := licenseNames(p.opts.License)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 && p.opts.LicenseTextFile != "" {
+		names = []string{""}
+	}
+
+	var written []string
+	for _, name := range names {
+		content, err := p.readLicense(name)
+		if err != nil {
+			return written, err
+		}
+		filePath := path
+		if len(names) > 1 {
+			filePath += "-" + licenseFileSuffix(name, names)
+		}
+		old, err := os.ReadFile(filePath)
+		if err != nil && !os.IsNotExist(err) {
+			return written, err
+		}
+		text, err := p.placeholders().Fill(string(content))
+		if err != nil {
+			return written, err
+		}
+		if err := p.writeFile(filePath, string(old), text); err != nil {
+			return written, err
+		}
+		written = append(written, filePath)
+	}
+	return written, nil
+}
+
+// expressionText returns the texts of the licenses of an SPDX license
+// expression, separated by a blank line
+func (p *Processor) expressionText(expression string) (string, error) {
+	return p.joinLicenses(expression, p.readLicense)
+}
+
+// expressionHeader is expressionText for file headers, with the short
+// notices of the licenses that have one
+func (p *Processor) expressionHeader(expression string) (string, error) {
+	return p.joinLicenses(expression, p.readLicenseHeader)
+}
+
+// joinLicenses returns the texts read by read for the licenses of an SPDX
+// license expression, separated by a blank line
+func (p *Processor) joinLicenses(expression string, read func(name string) ([]byte, error)) (string, error) {
+	names, err := licenseNames(expression)
+	if err != nil {
+		return "", err
+	}
+	if len(names) <= 1 {
+		content, err := read(strings.Join(names, ""))
+		return string(content), err
+	}
+
+	var texts []string
+	for _, name := range names {
+		content, err := read(name)
+		if err != nil {
+			return "", err
+		}
+		texts = append(texts, strings.TrimRight(string(content), "\n"))
+	}
+	return strings.Join(texts, "\n\n") + "\n", nil
+}
+
+// HeaderTemplate returns the text rendered into file headers, with the
+// [year] and [fullname] placeholders still in place: the HeaderFile or
+// Template file if given, the REUSE tags with REUSE, the SPDX short header with SPDX,
+// otherwise the short notice of the license or its full text
+func (p *Processor) HeaderTemplate() (string, error) {
+	return p.headerTemplate(p.opts)
+}
+
+func (p *Processor) headerTemplate(opts Options) (string, error) {
+	if opts.HeaderFile != "" {
+		content, err := os.ReadFile(opts.HeaderFile)
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	}
+
+	// Name the owners along with their emails if all of them have one
+	owner := "[fullname] <[email]>"
+	for _, o := range opts.Owners {
+		if o.Email == "" {
+			owner = "[fullname]"
+		}
+	}
+	if len(opts.Owners) == 0 {
+		owner = "[fullname]"
+	}
+
+	if opts.CopyrightOnly {
+		// Avoid a double period after names such as "ACME Inc."
+		period := "."
+		if owner == "[fullname]" && len(opts.Owners) > 0 && strings.HasSuffix(opts.Owners[len(opts.Owners)-1].Name, ".") {
+			period = ""
+		}
+		return "Copyright (c) [year] " + owner + period + " All rights reserved.", nil
+	}
+	if opts.Template == "" && opts.REUSE {
+		return "SPDX-FileCopyrightText: [year] " + owner + "\n" +
+			"SPDX-License-Identifier: " + SPDXID(opts.License), nil
+	}
+	if opts.Template == "" && opts.SPDX {
+		return "SPDX-License-Identifier: " + SPDXID(opts.License) + "\n" +
+			"Copyright (c) [year] " + owner, nil
+	}
+
+	if opts.Template == "" {
+		return p.expressionHeader(opts.License)
+	}
+	content, err := os.ReadFile(opts.Template)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// AvailableLicenses returns the sorted names of the embedded licenses and
+// those in licenseDir
+func AvailableLicenses(licenseDir string) ([]string, error) {
+	infos, err := Licenses(licenseDir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name
+	}
+	return names, nil
+}
+
+// Licenses describes the embedded licenses and those in licenseDir, sorted
+// by name. Custom licenses are described by their metadata block, if any,
+// and otherwise titled after the first line of their text.
+func Licenses(licenseDir string) ([]LicenseInfo, error) {
+	infos := make(map[string]LicenseInfo)
+
+	files, err := fs.ReadDir(embeddedLicenses, "licenses")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".txt")
+		infos[name] = catalog[name]
+	}
+
+	if licenseDir != "" {
+		customFiles, err := os.ReadDir(licenseDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range customFiles {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".txt" {
+				continue
+			}
+			name := strings.TrimSuffix(file.Name(), ".txt")
+			content, err := os.ReadFile(filepath.Join(licenseDir, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			meta, text, err := splitLicenseMeta(content)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Name(), err)
+			}
+			if meta.SPDXID == "" {
+				meta.SPDXID = SPDXID(name)
+			}
+			if meta.Title == "" {
+				title, _, _ := strings.Cut(strings.TrimSpace(string(text)), "\n")
+				meta.Title = strings.TrimSpace(title)
+			}
+			if meta.Description == "" {
+				meta.Description = "Custom license."
+			}
+			infos[name] = meta.info(name)
+		}
+	}
+
+	var sorted []LicenseInfo
+	for _, info := range infos {
+		sorted = append(sorted, info)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted, nil
+}
+
+// SearchLicenses returns the licenses whose name, SPDX identifier or title
+// match query, best matches first. A license matches if the letters of the
+// query appear in order, so "apache2" finds "apache-2.0"; contiguous matches
+// rank higher.
+func SearchLicenses(infos []LicenseInfo, query string) []LicenseInfo {
+	type match struct {
+		info  LicenseInfo
+		score int
+	}
+	var matches []match
+	for _, info := range infos {
+		best := -1
+		for _, field := range []string{info.Name, info.SPDXID, info.Title} {
+			if score := fuzzyScore(query, field); score > best {
+				best = score
+			}
+		}
+		if best >= 0 {
+			matches = append(matches, match{info, best})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	results := make([]LicenseInfo, len(matches))
+	for i, m := range matches {
+		results[i] = m.info
+	}
+	return results
+}
+
+// fuzzyScore rates how well query matches s, ignoring case and anything but
+// letters and digits, or returns -1 if it does not match
+func fuzzyScore(query, s string) int {
+	query = nonWord.ReplaceAllString(strings.ToLower(query), "")
+	s = nonWord.ReplaceAllString(strings.ToLower(s), "")
+	switch {
+	case query == s:
+		return 3
+	case strings.HasPrefix(s, query):
+		return 2
+	case strings.Contains(s, query):
+		return 1
+	}
+
+	// Fall back to the query letters appearing in order
+	i := 0
+	for j := 0; j < len(s) && i < len(query); j++ {
+		if s[j] == query[i] {
+			i++
+		}
+	}
+	if i == len(query) {
+		return 0
+	}
+	return -1
+}
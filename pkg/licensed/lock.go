@@ -0,0 +1,48 @@
+package licensed
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// LockFile is the advisory lock taken in the project directory by the runs
+// modifying files, so that two simultaneous runs do not corrupt them
+const LockFile = ".licensed.lock"
+
+// ErrLocked is returned when another run holds the lock on the project
+var ErrLocked = errors.New("another licensed run is modifying the project")
+
+// lock takes the lock on the project for a run modifying files, returning
+// the function releasing it. Dry runs modify nothing and take no lock.
+func (p *Processor) lock() (func(), error) {
+	if p.opts.DryRun {
+		return func() {}, nil
+	}
+	path := filepath.Join(p.opts.Dir, LockFile)
+	unlock, err := lockFile(path)
+	if errors.Is(err, ErrLocked) {
+		return nil, fmt.Errorf("%w: %s is held", err, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("locking %s: %w", path, err)
+	}
+	return func() {
+		if err := unlock(); err != nil {
+			p.log.Warn("cannot release lock", "path", path, "error", err)
+		}
+	}, nil
+}
+
+// isLockFile reports whether filePath is the lock of the project
+func (p *Processor) isLockFile(filePath string) bool {
+	if filepath.Base(filePath) != LockFile {
+		return false
+	}
+	a, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return false
+	}
+	b, err := filepath.Abs(p.opts.Dir)
+	return err == nil && a == b
+}
@@ -0,0 +1,25 @@
+//go:build !unix
+
+package licensed
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile creates path exclusively, failing with ErrLocked if it exists.
+// Without flock, a crashed run leaves the file behind, to be deleted by
+// hand.
+func lockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		f.Close()
+		return os.Remove(path)
+	}, nil
+}
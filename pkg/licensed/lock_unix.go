@@ -0,0 +1,34 @@
+//go:build unix
+
+package licensed
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive flock on path, failing with ErrLocked rather
+// than waiting if another process holds it. The lock dies with the process,
+// so a crashed run never leaves the project locked.
+func lockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	return func() error {
+		// Remove the file while still holding the lock
+		err := os.Remove(path)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
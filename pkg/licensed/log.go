@@ -0,0 +1,39 @@
+package licensed
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// discardLogger drops every record, for Processors without a Logger
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// skipFile records a file left untouched. Unknown extensions are warned
+// about since they usually call for a comment syntax mapping.
+func (p *Processor) skipFile(result *Result, reason, filePath string) {
+	if reason == "unknown type" {
+		p.log.Warn("unknown extension", "path", filePath)
+	} else {
+		p.log.Debug("skipped file", "path", filePath, "reason", reason)
+	}
+	result.skip(reason, filePath)
+}
+
+// fileError records a file that could not be processed, or that is
+// read-only and left alone
+func (p *Processor) fileError(result *Result, filePath string, err error) {
+	if errors.Is(err, ErrReadOnly) {
+		p.log.Warn("read-only file left untouched", "path", filePath, "error", err)
+		result.ReadOnly = append(result.ReadOnly, filePath)
+		return
+	}
+	p.log.Error("cannot process file", "path", filePath, "error", err)
+	result.Errors = append(result.Errors, Problem{Path: filePath, Message: err.Error()})
+}
+
+// problem records a REUSE compliance problem
+func (p *Processor) problem(result *Result, filePath, message string) {
+	p.log.Warn("reuse problem", "path", filePath, "problem", message)
+	result.Problems = append(result.Problems, Problem{Path: filePath, Message: message})
+}
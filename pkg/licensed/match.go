@@ -0,0 +1,72 @@
+package licensed
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// nonWord matches the comment characters, punctuation and whitespace
+	// ignored when comparing headers
+	nonWord = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+	// yearRange matches a year or a range or list of years
+	yearRange = regexp.MustCompile(`\b(?:19|20)\d{2}(?:\s*[-–,]\s*(?:(?:19|20)\d{2}|present))*\b`)
+)
+
+// normalizeHeader reduces header text to its words so that headers differing
+// only in comment style, wrapping, punctuation, case or years compare equal
+func normalizeHeader(text string) string {
+	text = strings.ToLower(text)
+	text = yearRange.ReplaceAllString(text, " year ")
+	text = nonWord.ReplaceAllString(text, " ")
+	return strings.TrimSpace(text)
+}
+
+// HasLicenseHeader reports whether the top of content, after any preamble
+// lines and optionally a file-level docstring, carries header. Only the
+// first lines are inspected, allowing for the header to have been wrapped
+// differently.
+func HasLicenseHeader(content, header, filePath string) bool {
+	_, text := splitBOM(content)
+	_, rest := splitPreamble(text, filePath)
+	header = withLineEnding(header, lineEnding(text))
+	if startsWithHeader(rest, header) {
+		return true
+	}
+	_, rest = splitDocstring(rest, filePath)
+	return startsWithHeader(rest, header)
+}
+
+// hasLicenseHeader is HasLicenseHeader, except that a HeaderFile header
+// must be there byte for byte
+func (p *Processor) hasLicenseHeader(content, header, filePath string) bool {
+	if p.opts.HeaderFile == "" {
+		return HasLicenseHeader(content, header, filePath)
+	}
+	_, text := splitBOM(content)
+	_, rest := splitPreamble(text, filePath)
+	header = withLineEnding(header, lineEnding(text))
+	if strings.HasPrefix(rest, header) {
+		return true
+	}
+	_, rest = splitDocstring(rest, filePath)
+	return strings.HasPrefix(rest, header)
+}
+
+// startsWithHeader reports whether rest starts with header
+func startsWithHeader(rest, header string) bool {
+	// Fast path for an exact match
+	if strings.HasPrefix(rest, header) {
+		return true
+	}
+
+	headerLines := strings.Count(header, "\n") + 1
+	top := strings.SplitN(rest, "\n", 2*headerLines+10)
+	if len(top) > 2*headerLines+9 {
+		top = top[:2*headerLines+9]
+	}
+
+	expected := normalizeHeader(header)
+	return expected != "" && strings.HasPrefix(normalizeHeader(strings.Join(top, "\n"))+" ", expected+" ")
+}
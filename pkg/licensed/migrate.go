@@ -0,0 +1,205 @@
+package licensed
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MigrationSources are the tools whose configuration can be converted, by
+// MigrateAddlicense and MigrateLicenseEye
+var MigrationSources = []string{"addlicense", "license-eye"}
+
+// Migration is the configuration of another tool converted for licensed
+type Migration struct {
+	Config Config
+	// Template is a text/template for the header converted from a custom
+	// header of the tool, to be saved to a file and set as Config.Template
+	Template string
+	// Notes tell which settings could not be converted
+	Notes []string
+}
+
+// Note records a setting that could not be converted
+func (m *Migration) Note(format string, args ...any) {
+	m.Notes = append(m.Notes, fmt.Sprintf(format, args...))
+}
+
+// addlicenseTypes maps the -l license types of addlicense to SPDX
+// identifiers
+var addlicenseTypes = map[string]string{
+	"apache": "Apache-2.0",
+	"bsd":    "BSD-3-Clause",
+	"mit":    "MIT",
+	"mpl":    "MPL-2.0",
+}
+
+// MigrateAddlicense converts the command line of addlicense, with or
+// without the command name, e.g. -c "ACME Inc." -l mit -ignore "**/*.pb.go".
+// A custom license file given by -f is read into the template.
+func MigrateAddlicense(args []string) (*Migration, error) {
+	if len(args) > 0 && (args[0] == "addlicense" || strings.HasSuffix(args[0], "/addlicense")) {
+		args = args[1:]
+	}
+
+	// Parse the flags as addlicense does, with the flag package
+	flags := flag.NewFlagSet("addlicense", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	holder := flags.String("c", "Google LLC", "")
+	license := flags.String("l", "apache", "")
+	licenseFile := flags.String("f", "", "")
+	year := flags.String("y", "", "")
+	var spdx spdxFlag
+	flags.Var(&spdx, "s", "")
+	var ignore stringList
+	flags.Var(&ignore, "ignore", "")
+	flags.Bool("check", false, "")
+	flags.Bool("v", false, "")
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("parsing addlicense flags: %w", err)
+	}
+
+	m := &Migration{}
+	m.Config.Owner = *holder
+	m.Config.Year = *year
+	m.Config.Ignore = ignore
+	if *licenseFile != "" {
+		content, err := os.ReadFile(*licenseFile)
+		if err != nil {
+			return nil, err
+		}
+		// addlicense fills in {{.Holder}} where licensed has {{.Owner}}
+		m.Template = strings.ReplaceAll(string(content), ".Holder", ".Owner")
+	} else {
+		id, ok := addlicenseTypes[strings.ToLower(*license)]
+		if !ok {
+			return nil, fmt.Errorf("unknown addlicense license type %q", *license)
+		}
+		m.Config.License = id
+	}
+	switch spdx {
+	case "only":
+		spdxOnly := true
+		m.Config.SPDX = &spdxOnly
+	case "true":
+		m.Note("-s adds an SPDX-License-Identifier line to the full header, which licensed does not; use spdx: true for the identifier alone")
+	}
+	if paths := slices.DeleteFunc(flags.Args(), func(path string) bool { return path == "." }); len(paths) > 0 {
+		m.Note("the paths %s are not converted; licensed processes the project directory or the files it is given", strings.Join(paths, " "))
+	}
+	return m, nil
+}
+
+// licenseEyeHeader is a header section of a license-eye .licenserc.yaml
+type licenseEyeHeader struct {
+	License struct {
+		SPDXID         string `yaml:"spdx-id"`
+		CopyrightOwner string `yaml:"copyright-owner"`
+		CopyrightYear  string `yaml:"copyright-year"`
+		SoftwareName   string `yaml:"software-name"`
+		Content        string `yaml:"content"`
+		Pattern        string `yaml:"pattern"`
+	} `yaml:"license"`
+	Paths       []string `yaml:"paths"`
+	PathsIgnore []string `yaml:"paths-ignore"`
+}
+
+// MigrateLicenseEye converts a license-eye .licenserc.yaml. Its header
+// section may be a list, in which case the headers after the first become
+// path overrides.
+func MigrateLicenseEye(data []byte) (*Migration, error) {
+	var doc struct {
+		Header     yaml.Node `yaml:"header"`
+		Dependency yaml.Node `yaml:"dependency"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing license-eye configuration: %w", err)
+	}
+	var headers []licenseEyeHeader
+	switch doc.Header.Kind {
+	case yaml.SequenceNode:
+		if err := doc.Header.Decode(&headers); err != nil {
+			return nil, fmt.Errorf("parsing license-eye configuration: %w", err)
+		}
+	case yaml.MappingNode:
+		var header licenseEyeHeader
+		if err := doc.Header.Decode(&header); err != nil {
+			return nil, fmt.Errorf("parsing license-eye configuration: %w", err)
+		}
+		headers = append(headers, header)
+	}
+	if len(headers) == 0 {
+		return nil, fmt.Errorf("license-eye configuration has no header section")
+	}
+
+	m := &Migration{}
+	first := headers[0]
+	m.Config.License = first.License.SPDXID
+	m.Config.Owner = first.License.CopyrightOwner
+	m.Config.Year = first.License.CopyrightYear
+	m.Config.Project = first.License.SoftwareName
+	m.Config.Ignore = first.PathsIgnore
+	if content := strings.TrimSpace(first.License.Content); content != "" {
+		// license-eye fills in [year], [owner] and [software-name]
+		m.Template = strings.NewReplacer("[year]", "{{.Year}}", "[owner]", "{{.Owner}}", "[software-name]", "{{.Project}}").Replace(content) + "\n"
+		m.Config.License = ""
+	}
+	if first.License.Pattern != "" {
+		m.Note("the license pattern of the header is not converted; licensed matches headers by their words")
+	}
+	if len(first.Paths) > 0 && !(len(first.Paths) == 1 && first.Paths[0] == "**") {
+		m.Note("the paths %s are not converted; use --include to restrict licensed to them", strings.Join(first.Paths, ", "))
+	}
+
+	for _, header := range headers[1:] {
+		if header.License.Content != "" || len(header.PathsIgnore) > 0 {
+			m.Note("the content and ignored paths of the header for %s are not converted", strings.Join(header.Paths, ", "))
+		}
+		for _, path := range header.Paths {
+			m.Config.Paths = append(m.Config.Paths, PathConfig{
+				Path:    path,
+				License: header.License.SPDXID,
+				Owner:   header.License.CopyrightOwner,
+			})
+		}
+	}
+	if !doc.Dependency.IsZero() {
+		m.Note("the dependency section is not converted; see the dependencies policy of licensed deps")
+	}
+	return m, nil
+}
+
+// spdxFlag is the -s flag of addlicense, which is either a boolean or
+// "only"
+type spdxFlag string
+
+func (f *spdxFlag) String() string { return string(*f) }
+
+func (f *spdxFlag) Set(value string) error {
+	switch value {
+	case "true", "only":
+		*f = spdxFlag(value)
+	case "false":
+		*f = ""
+	default:
+		return fmt.Errorf("invalid -s value %q", value)
+	}
+	return nil
+}
+
+func (f *spdxFlag) IsBoolFlag() bool { return true }
+
+// stringList is a flag repeated for several values
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,230 @@
+package licensed
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// notebookExtensions maps the kernel languages of Jupyter notebooks to the
+// file extension whose comment syntax their code cells use
+var notebookExtensions = map[string]string{
+	"python":     ".py",
+	"r":          ".r",
+	"julia":      ".jl",
+	"scala":      ".scala",
+	"javascript": ".js",
+	"typescript": ".ts",
+	"ruby":       ".rb",
+	"bash":       ".sh",
+	"c++":        ".cpp",
+	"go":         ".go",
+	"rust":       ".rs",
+	"java":       ".java",
+	"sql":        ".sql",
+	"lua":        ".lua",
+	"haskell":    ".hs",
+}
+
+// notebookIndent matches the indentation of the first nested line of a
+// notebook, which nbformat writes with one space
+var notebookIndent = regexp.MustCompile(`\n([ \t]+)"`)
+
+func isNotebook(filePath string) bool {
+	return strings.EqualFold(filepath.Ext(filePath), ".ipynb")
+}
+
+// notebookCells returns the cells of a decoded notebook
+func notebookCells(nb map[string]any) []any {
+	cells, _ := nb["cells"].([]any)
+	return cells
+}
+
+// notebookLanguage returns the kernel language of a decoded notebook
+func notebookLanguage(nb map[string]any) string {
+	metadata, _ := nb["metadata"].(map[string]any)
+	kernelspec, _ := metadata["kernelspec"].(map[string]any)
+	if language, ok := kernelspec["language"].(string); ok && language != "" {
+		return strings.ToLower(language)
+	}
+	languageInfo, _ := metadata["language_info"].(map[string]any)
+	language, _ := languageInfo["name"].(string)
+	return strings.ToLower(language)
+}
+
+// cellSource returns the source of a cell, stored as a string or as a list
+// of lines
+func cellSource(cell map[string]any) string {
+	switch source := cell["source"].(type) {
+	case string:
+		return source
+	case []any:
+		var b strings.Builder
+		for _, line := range source {
+			if s, ok := line.(string); ok {
+				b.WriteString(s)
+			}
+		}
+		return b.String()
+	}
+	return ""
+}
+
+// sourceLines splits source into the list of lines nbformat stores, each
+// keeping its line feed
+func sourceLines(source string) []any {
+	lines := []any{}
+	for _, line := range strings.SplitAfter(source, "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// notebookHeader returns the index of the cell holding the license header
+// of a decoded notebook, the first code cell commented in the kernel
+// language, and the header it should carry. Notebooks without code cells or
+// in a language without known comment syntax get a raw cell at the top
+// instead, with the plain license text, and -1 is returned.
+func (p *Processor) notebookHeader(nb map[string]any, licenseContent string) (int, string) {
+	if syntax, ok := p.commentSyntaxes[notebookExtensions[notebookLanguage(nb)]]; ok {
+		for i, cell := range notebookCells(nb) {
+			if cell, ok := cell.(map[string]any); ok && cell["cell_type"] == "code" {
+				return i, p.formatHeader("", licenseContent, syntax)
+			}
+		}
+	}
+	return -1, licenseContent
+}
+
+// readNotebook decodes the notebook at filePath, keeping numbers as written
+func readNotebook(filePath string) ([]byte, map[string]any, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var nb map[string]any
+	if err := decoder.Decode(&nb); err != nil {
+		return nil, nil, fmt.Errorf("reading notebook: %w", err)
+	}
+	return content, nb, nil
+}
+
+// notebookHasHeader reports whether the notebook at filePath carries the
+// license header in its first code cell or a leading raw cell
+func (p *Processor) notebookHasHeader(filePath, licenseContent string) (bool, error) {
+	_, nb, err := readNotebook(filePath)
+	if err != nil {
+		return false, err
+	}
+	return p.findNotebookHeader(nb, licenseContent), nil
+}
+
+// findNotebookHeader is notebookHasHeader for a decoded notebook
+func (p *Processor) findNotebookHeader(nb map[string]any, licenseContent string) bool {
+	cells := notebookCells(nb)
+	index, header := p.notebookHeader(nb, licenseContent)
+	if index >= 0 && HasLicenseHeader(cellSource(cells[index].(map[string]any)), header, "") {
+		return true
+	}
+
+	// A leading raw cell holds the header otherwise, and is accepted for
+	// notebooks with code cells too
+	if len(cells) > 0 {
+		if cell, ok := cells[0].(map[string]any); ok && cell["cell_type"] == "raw" {
+			return HasLicenseHeader(cellSource(cell), licenseContent, "")
+		}
+	}
+	return false
+}
+
+// addNotebookHeader adds the license header to the notebook at filePath,
+// reporting whether it was changed. The notebook is written back the way
+// nbformat writes it: sorted keys, its original indentation and a final
+// line feed.
+func (p *Processor) addNotebookHeader(filePath, licenseContent string) (bool, error) {
+	content, nb, err := readNotebook(filePath)
+	if err != nil {
+		return false, err
+	}
+	if p.findNotebookHeader(nb, licenseContent) {
+		return false, nil
+	}
+
+	index, header := p.notebookHeader(nb, licenseContent)
+	if index >= 0 {
+		// Comment the header at the top of the first code cell
+		cell := notebookCells(nb)[index].(map[string]any)
+		cell["source"] = sourceLines(header + "\n" + strings.Repeat("\n", p.opts.BlankLines) + cellSource(cell))
+	} else {
+		// Insert a raw cell with the license text
+		cell := map[string]any{
+			"cell_type": "raw",
+			"metadata":  map[string]any{},
+			"source":    sourceLines(header),
+		}
+		if minor, err := json.Number(fmt.Sprint(nb["nbformat_minor"])).Int64(); err == nil && minor >= 5 {
+			cell["id"] = newCellID()
+		}
+		nb["cells"] = append([]any{cell}, notebookCells(nb)...)
+	}
+
+	indent := " "
+	if match := notebookIndent.FindSubmatch(content); match != nil {
+		indent = string(match[1])
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(nb); err != nil {
+		return false, err
+	}
+	return true, p.writeFile(filePath, string(content), buf.String())
+}
+
+// stampNotebook adds the license header to the notebook at filePath, or
+// only reports whether it is missing with checkOnly
+func (p *Processor) stampNotebook(result *Result, filePath, licenseContent string, checkOnly bool) error {
+	if checkOnly {
+		found, err := p.notebookHasHeader(filePath, licenseContent)
+		if err != nil {
+			return err
+		}
+		if !found {
+			result.Missing = append(result.Missing, filePath)
+			p.log.Warn("missing license header", "path", filePath)
+			result.line(filePath, 1)
+			return nil
+		}
+		result.Licensed = append(result.Licensed, filePath)
+		return nil
+	}
+
+	changed, err := p.addNotebookHeader(filePath, licenseContent)
+	if err != nil {
+		return err
+	}
+	if changed {
+		result.Changed = append(result.Changed, filePath)
+	} else {
+		result.Licensed = append(result.Licensed, filePath)
+	}
+	return nil
+}
+
+// newCellID returns a random cell id as nbformat 4.5 requires
+func newCellID() string {
+	id := make([]byte, 4)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,66 @@
+package licensed
+
+import (
+	"os"
+	"strings"
+)
+
+// NeedsNotice reports whether a license of the License expression comes
+// with a NOTICE file, as Apache-2.0 does
+func (p *Processor) NeedsNotice() bool {
+	names, err := licenseNames(p.opts.License)
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		if catalog[catalogName(name)].Notice {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteNoticeFile writes the NOTICE file at path: the project name, the
+// copyright line and the Notice entries, separated by blank lines. An
+// existing file keeps its content, only its copyright line is brought up to
+// date and the missing entries are appended.
+func (p *Processor) WriteNoticeFile(path string) error {
+	values := p.placeholders()
+	copyright, err := values.Fill("Copyright [year] [fullname]")
+	if err != nil {
+		return err
+	}
+
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	content := string(old)
+	if content == "" {
+		content = values.Project + "\n" + copyright + "\n"
+	} else {
+		// Replace the first copyright line, or add one below the title
+		lines := strings.Split(content, "\n")
+		replaced := false
+		for i, line := range lines {
+			if strings.HasPrefix(strings.ToLower(strings.TrimSpace(line)), "copyright ") {
+				lines[i] = copyright
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			lines = append(lines[:1], append([]string{copyright}, lines[1:]...)...)
+		}
+		content = strings.Join(lines, "\n")
+	}
+
+	for _, entry := range p.opts.Notice {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.Contains(content, entry) {
+			continue
+		}
+		content = strings.TrimRight(content, "\n") + "\n\n" + entry + "\n"
+	}
+	return p.writeFile(path, string(old), content)
+}
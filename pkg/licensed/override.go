@@ -0,0 +1,80 @@
+package licensed
+
+// PathOverride replaces the license settings for the files matching a
+// gitignore-style pattern, e.g. for the libraries of a monorepo that are
+// licensed differently from its tools
+type PathOverride struct {
+	// Pattern selects the files, relative to the project directory
+	Pattern string
+	// License replaces Options.License if set
+	License string
+	// Owners replace Options.Owners if set
+	Owners []Owner
+	// Template replaces Options.Template if set
+	Template string
+}
+
+// pathOverride is a PathOverride with its pattern parsed
+type pathOverride struct {
+	PathOverride
+	rules []ignoreRule
+}
+
+func parsePathOverrides(overrides []PathOverride) []pathOverride {
+	parsed := make([]pathOverride, len(overrides))
+	for i, override := range overrides {
+		parsed[i] = pathOverride{PathOverride: override, rules: parseIgnoreRules([]byte(override.Pattern))}
+	}
+	return parsed
+}
+
+// optionsFor returns the options applying to filePath along with the index
+// of the path override they come from, or -1. The last matching override
+// wins, as with gitignore patterns.
+func (p *Processor) optionsFor(filePath string) (Options, int) {
+	opts, index := p.opts, -1
+	rel := p.relPath(filePath)
+	for i, override := range p.overrides {
+		if matchPath(override.rules, rel, false) {
+			index = i
+		}
+	}
+	if index < 0 {
+		return opts, index
+	}
+
+	override := p.overrides[index]
+	if override.License != "" {
+		opts.License = override.License
+		opts.Template = ""
+		opts.LicenseTextFile = ""
+		opts.HeaderFile = ""
+	}
+	if len(override.Owners) > 0 {
+		opts.Owners = override.Owners
+	}
+	if override.Template != "" {
+		opts.Template = override.Template
+		opts.HeaderFile = ""
+	}
+	return opts, index
+}
+
+// rendererFor returns the header renderer for filePath, creating the one of
+// its path override on first use. It returns nil if no license applies.
+func (p *Processor) rendererFor(renderers map[int]*headerRenderer, filePath string) (*headerRenderer, error) {
+	opts, index := p.optionsFor(filePath)
+	if renderer, ok := renderers[index]; ok {
+		return renderer, nil
+	}
+	if opts.License == "" && opts.Template == "" && opts.LicenseTextFile == "" && opts.HeaderFile == "" && !opts.CopyrightOnly {
+		return nil, nil
+	}
+
+	renderer, err := p.newHeaderRenderer(opts)
+	if err != nil {
+		return nil, err
+	}
+	renderers[index] = renderer
+	return renderer, nil
+}
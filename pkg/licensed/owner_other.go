@@ -0,0 +1,10 @@
+//go:build !unix
+
+package licensed
+
+import "os"
+
+// copyOwner is a no-op on systems without POSIX file ownership
+func copyOwner(path string, info os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package licensed
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner gives path the owner and group of the file described by info.
+// Only the superuser may give files away, so lacking the permission is not
+// an error.
+func copyOwner(path string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if err := os.Lchown(path, int(stat.Uid), int(stat.Gid)); err != nil && !os.IsPermission(err) {
+		return err
+	}
+	return nil
+}
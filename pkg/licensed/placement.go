@@ -0,0 +1,230 @@
+package licensed
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// pythonEncoding matches a PEP 263 source encoding declaration
+var pythonEncoding = regexp.MustCompile(`^[ \t\f]*#.*?coding[:=][ \t]*[-_.a-zA-Z0-9]+`)
+
+// pythonDocstringStart matches the opening quotes of a Python docstring
+var pythonDocstringStart = regexp.MustCompile(`^(?i:[rub]{0,2})("""|''')`)
+
+// fileDocTag matches the JSDoc tags marking a comment as documenting the
+// whole file
+var fileDocTag = regexp.MustCompile(`@(?:file|fileoverview|overview|module)\b`)
+
+// Header positions
+const (
+	// PositionTop places the header at the top of the file, below any
+	// preamble
+	PositionTop = "top"
+	// PositionAfterDocstring places the header below the file-level
+	// documentation: a Python module docstring or a leading Javadoc comment
+	PositionAfterDocstring = "after-docstring"
+)
+
+// dockerfileDirective matches a Dockerfile parser directive, which is only
+// honored before any other comment
+var dockerfileDirective = regexp.MustCompile(`^#[ \t]*[a-zA-Z]+[ \t]*=`)
+
+// preambleLength returns the number of leading lines that must stay above
+// the license header: a shebang, a Python encoding declaration, an XML
+// declaration, a PHP opening tag, Go build constraints, Dockerfile parser
+// directives, Markdown front matter or YAML directives
+func preambleLength(lines []string, filePath string) int {
+	ext := filepath.Ext(filePath)
+	switch strings.ToLower(ext) {
+	case ".md", ".markdown", ".mdx", ".adoc", ".asciidoc":
+		return frontMatterLength(lines)
+	case ".yaml", ".yml":
+		return yamlDirectivesLength(lines)
+	}
+
+	var n int
+	if n < len(lines) && strings.HasPrefix(lines[n], "#!") {
+		n++
+	}
+
+	if ext == ".go" {
+		return n + goBuildConstraintsLength(lines[n:])
+	}
+
+	if isDockerfile(filePath) {
+		for n < len(lines) && dockerfileDirective.MatchString(lines[n]) {
+			n++
+		}
+		return n
+	}
+
+	// The encoding declaration must be on the first or second line
+	if ext == ".py" && n < len(lines) && n < 2 && pythonEncoding.MatchString(lines[n]) {
+		n++
+	}
+
+	if n < len(lines) {
+		line := strings.TrimSpace(lines[n])
+		if strings.HasPrefix(line, "<?xml") || strings.HasPrefix(line, "<?php") {
+			n++
+		}
+	}
+	return n
+}
+
+// splitPreamble splits content into the preamble that must stay above the
+// license header and the rest of the file
+func splitPreamble(content, filePath string) (string, string) {
+	lines := strings.SplitAfter(content, "\n")
+	n := preambleLength(lines, filePath)
+	return strings.Join(lines[:n], ""), strings.Join(lines[n:], "")
+}
+
+// preambleLength is preambleLength, also keeping the file-level
+// documentation above the header with PositionAfterDocstring
+func (p *Processor) preambleLength(lines []string, filePath string) int {
+	n := preambleLength(lines, filePath)
+	if p.opts.Position == PositionAfterDocstring {
+		n += docstringLength(lines[n:], filePath)
+	}
+	return n
+}
+
+// docstringLength returns the number of leading lines holding the
+// documentation of the whole file, including the blank line following it: a
+// Python module docstring, a Javadoc comment in JVM languages, or a JSDoc
+// comment tagged @file, @fileoverview or @module in JavaScript and TypeScript
+func docstringLength(lines []string, filePath string) int {
+	var n int
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".py", ".pyi":
+		n = pythonDocstringLength(lines)
+	case ".java", ".kt", ".kts", ".scala", ".groovy":
+		n = docCommentLength(lines, false)
+	case ".js", ".mjs", ".cjs", ".jsx", ".ts", ".mts", ".cts", ".tsx":
+		n = docCommentLength(lines, true)
+	}
+	if n > 0 && n < len(lines) && strings.TrimSpace(lines[n]) == "" {
+		n++
+	}
+	return n
+}
+
+// pythonDocstringLength returns the number of lines of the triple-quoted
+// docstring opening lines, or 0
+func pythonDocstringLength(lines []string) int {
+	if len(lines) == 0 {
+		return 0
+	}
+	line := strings.TrimSpace(lines[0])
+	match := pythonDocstringStart.FindStringSubmatch(line)
+	if match == nil {
+		return 0
+	}
+	quotes := match[1]
+	if strings.Contains(line[len(match[0]):], quotes) {
+		return 1
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.Contains(lines[i], quotes) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// docCommentLength returns the number of lines of the /** comment opening
+// lines, or 0. With tagged, the comment must carry a file-level tag.
+func docCommentLength(lines []string, tagged bool) int {
+	if len(lines) == 0 || !strings.HasPrefix(strings.TrimSpace(lines[0]), "/**") {
+		return 0
+	}
+	for i, line := range lines {
+		if i == 0 {
+			line = strings.TrimPrefix(strings.TrimSpace(line), "/**")
+		}
+		if !strings.Contains(line, "*/") {
+			continue
+		}
+		if tagged && !fileDocTag.MatchString(strings.Join(lines[:i+1], "\n")) {
+			return 0
+		}
+		return i + 1
+	}
+	return 0
+}
+
+// splitDocstring splits content following the preamble into the file-level
+// documentation and the rest of the file
+func splitDocstring(content, filePath string) (string, string) {
+	lines := strings.SplitAfter(content, "\n")
+	n := docstringLength(lines, filePath)
+	return strings.Join(lines[:n], ""), strings.Join(lines[n:], "")
+}
+
+// frontMatterLength returns the number of leading lines holding a YAML
+// front matter block between --- lines or a TOML one between +++ lines,
+// including a blank line following it
+func frontMatterLength(lines []string) int {
+	if len(lines) == 0 {
+		return 0
+	}
+	open := strings.TrimSpace(lines[0])
+	if open != "---" && open != "+++" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line != open && (open != "---" || line != "...") {
+			continue
+		}
+		if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) == "" {
+			return i + 2
+		}
+		return i + 1
+	}
+	return 0
+}
+
+// yamlDirectivesLength returns the number of leading lines holding YAML
+// directives such as %YAML 1.2 and the --- marker that ends them. A comment
+// above the directives would make some parsers reject the file.
+func yamlDirectivesLength(lines []string) int {
+	var n int
+	for n < len(lines) && strings.HasPrefix(lines[n], "%") {
+		n++
+	}
+	if n > 0 && n < len(lines) && strings.HasPrefix(lines[n], "---") {
+		n++
+	}
+	return n
+}
+
+// goBuildConstraintsLength returns the number of leading lines holding Go
+// build constraints, including the blank line that must follow them
+func goBuildConstraintsLength(lines []string) int {
+	var n int
+	for n < len(lines) && isGoBuildConstraint(lines[n]) {
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+
+	// Without the blank line the constraints become part of the package doc
+	if n < len(lines) && strings.TrimSpace(lines[n]) == "" {
+		n++
+	}
+	return n
+}
+
+func isGoBuildConstraint(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "//go:build") || strings.HasPrefix(line, "// +build")
+}
+
+func isDockerfile(filePath string) bool {
+	name := strings.ToLower(filepath.Base(filePath))
+	return name == "dockerfile" || strings.HasPrefix(name, "dockerfile.") || strings.HasSuffix(name, ".dockerfile")
+}
@@ -0,0 +1,132 @@
+package licensed
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PolicyRule is a rule of the license policy enforced by Check on the
+// headers of the files it covers, e.g. that every file under src/ carries
+// Apache-2.0, or that no file carries a GPL license. License entries are
+// SPDX identifiers or catalog names, optionally ending in a * wildcard, and
+// "copyleft" stands for every copyleft license.
+type PolicyRule struct {
+	// Name identifies the rule in the failure messages
+	Name string `yaml:"name,omitempty" toml:"name,omitempty"`
+	// Paths are gitignore-style patterns of the files the rule covers,
+	// relative to the project directory. The rule covers every file if
+	// empty.
+	Paths []string `yaml:"paths,omitempty" toml:"paths,omitempty"`
+	// Require is the license the headers must carry
+	Require string `yaml:"require,omitempty" toml:"require,omitempty"`
+	// Forbid lists licenses the headers must not carry
+	Forbid []string `yaml:"forbid,omitempty" toml:"forbid,omitempty"`
+	// Exempt excludes the files it covers from every rule of the policy
+	Exempt bool `yaml:"exempt,omitempty" toml:"exempt,omitempty"`
+	// Message replaces the failure message of the rule
+	Message string `yaml:"message,omitempty" toml:"message,omitempty"`
+}
+
+// policyRule is a PolicyRule with its paths parsed
+type policyRule struct {
+	PolicyRule
+	rules []ignoreRule
+}
+
+func parsePolicy(policy []PolicyRule) []policyRule {
+	parsed := make([]policyRule, len(policy))
+	for i, rule := range policy {
+		parsed[i] = policyRule{PolicyRule: rule, rules: parseIgnoreRules([]byte(strings.Join(rule.Paths, "\n")))}
+	}
+	return parsed
+}
+
+// covers reports whether the rule applies to rel, a path relative to the
+// project directory
+func (rule policyRule) covers(rel string) bool {
+	return len(rule.Paths) == 0 || matchPath(rule.rules, rel, false)
+}
+
+// label names the rule in failure messages, by its name, its paths or its
+// position
+func (rule policyRule) label(index int) string {
+	switch {
+	case rule.Name != "":
+		return rule.Name
+	case len(rule.Paths) > 0:
+		return strings.Join(rule.Paths, ", ")
+	}
+	return fmt.Sprintf("rule %d", index+1)
+}
+
+// check returns why a header of license breaks the rule, or "" if it
+// complies. license is an SPDX expression, "unknown" or empty as in a
+// Detection.
+func (rule policyRule) check(license string) string {
+	names, err := licenseNames(license)
+	if err != nil || len(names) == 0 {
+		names = []string{license}
+	}
+	found := license
+	switch license {
+	case "":
+		found = "no license header"
+	case "unknown":
+		found = "an unrecognized license"
+	}
+
+	if rule.Require != "" {
+		var carried bool
+		for _, name := range names {
+			carried = carried || name != "" && name != "unknown" && matchesLicense([]string{rule.Require}, name)
+		}
+		if !carried {
+			return fmt.Sprintf("requires %s, found %s", SPDXID(rule.Require), found)
+		}
+	}
+	for _, name := range names {
+		if name != "" && name != "unknown" && matchesLicense(rule.Forbid, name) {
+			return fmt.Sprintf("forbids %s", name)
+		}
+	}
+	return ""
+}
+
+// checkPolicy records a violation in result for every rule of the policy
+// the header of filePath breaks, unless an exempting rule covers the file
+func (p *Processor) checkPolicy(result *Result, filePath string, commentSyntax CommentSyntax, known []knownLicense) error {
+	rel := p.relPath(filePath)
+	var rules []int
+	for i, rule := range p.policy {
+		if !rule.covers(rel) {
+			continue
+		}
+		if rule.Exempt {
+			return nil
+		}
+		rules = append(rules, i)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	content, err := readText(filePath)
+	if err != nil {
+		return err
+	}
+	detection := detectLicense(content, filePath, commentSyntax, known)
+	for _, i := range rules {
+		rule := p.policy[i]
+		reason := rule.check(detection.License)
+		if reason == "" {
+			continue
+		}
+		message := rule.label(i) + ": " + reason
+		if rule.Message != "" {
+			message = rule.label(i) + ": " + rule.Message
+		}
+		p.log.Warn("license policy violation", "path", filePath, "rule", rule.label(i), "reason", reason)
+		result.Violations = append(result.Violations, Problem{Path: filePath, Message: message})
+	}
+	return nil
+}
@@ -0,0 +1,1108 @@
+package licensed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Options configures a Processor. The zero value of every field is usable,
+// except that Dir must name the project directory and License or Template
+// must select the header text for Add and Check.
+type Options struct {
+	// License is the name of the license in the catalog, e.g. "mit"
+	License string
+	// Owners are the copyright holders filled in for [fullname]
+	Owners []Owner
+	// Year is filled in for [year]
+	Year string
+	// Dir is the project directory to process
+	Dir string
+
+	// Template is a text/template file used for the header instead of the
+	// license text, executed with TemplateData and functions such as
+	// {{ now.Year }}, {{ gitUser }}, {{ relpath }} and {{ env "NAME" }}
+	Template string
+	// SPDX selects a short SPDX-License-Identifier header
+	SPDX bool
+	// CopyrightOnly selects a header of just the copyright line, without
+	// any license, which may then be empty
+	CopyrightOnly bool
+	// REUSE follows the REUSE specification: files get SPDX-FileCopyrightText
+	// and SPDX-License-Identifier tags, license texts go to LICENSES/ and
+	// files that cannot carry a header are covered by .reuse/dep5
+	REUSE bool
+	// LicenseDir holds custom license texts overriding the bundled ones
+	LicenseDir string
+	// LicenseTextFile is a plain text file with the license body, e.g. a
+	// proprietary EULA, used instead of the catalog text of License, which
+	// may then be empty
+	LicenseTextFile string
+	// HeaderFile is a file with the exact header text, e.g. one approved by
+	// legal, used instead of the text selected by License or Template. It
+	// is commented out as is, without filling in placeholders or wrapping
+	// lines, and Check requires it byte for byte.
+	HeaderFile string
+	// FullText puts the full license text in file headers even for
+	// licenses with a short notice for them, such as Apache-2.0
+	FullText bool
+	// Offline forbids downloading license texts missing from the catalog
+	Offline bool
+
+	// Notice lists attribution entries for the NOTICE file, e.g. "This
+	// product includes software developed by Example Corp."
+	Notice []string
+
+	// Dependencies restricts the licenses of the dependencies reported by
+	// the Dependencies method
+	Dependencies DependencyPolicy
+	// Policy lists the rules on the licenses of the file headers enforced
+	// by Check
+	Policy []PolicyRule
+	// Handlers hand the files they match over to external commands instead
+	// of commenting them, for Add, Check and Remove
+	Handlers []FileHandler
+
+	// Project, Organization and URL fill in the [project], [organization]
+	// and [url] placeholders. Project defaults to the name of Dir.
+	Project      string
+	Organization string
+	URL          string
+	// Checksum ends the header with a marker holding a checksum of its text,
+	// e.g. "licensed: sha256:…", so that Strict can detect edited headers
+	Checksum bool
+	// Strict makes Check verify the checksum marker of each header, listing
+	// the headers without a matching one as Modified
+	Strict bool
+	// Position is where the header goes: PositionTop, the default, or
+	// PositionAfterDocstring
+	Position string
+	// BlankLines is the number of blank lines between header and code
+	BlankLines int
+	// Width is the column header lines are wrapped at, 0 disables wrapping
+	Width int
+
+	// YearFromGit fills in each file's first and last commit years
+	YearFromGit bool
+	// NoGitignore processes files ignored by .gitignore
+	NoGitignore bool
+	// NoDefaultIgnores processes the dependency and build output directories
+	// skipped by default, node_modules anywhere and vendor, dist, build and
+	// target at the top of the project
+	NoDefaultIgnores bool
+	// TrackedOnly only processes the files tracked by git if Dir is in a
+	// git work tree, leaving out untracked scratch files and artifacts
+	TrackedOnly bool
+	// Files are the only files to process, instead of walking Dir
+	Files []string
+	// StagedOnly only processes the files staged for commit
+	StagedOnly bool
+	// ChangedSince only processes the files changed since the merge base of
+	// this git ref and HEAD
+	ChangedSince string
+	// MaxFileSize is the size in bytes above which files are skipped, such
+	// as minified bundles, datasets and lockfiles. 0 disables the limit.
+	MaxFileSize int64
+	// FollowSymlinks walks into symbolically linked directories and
+	// processes linked files, which are skipped otherwise
+	FollowSymlinks bool
+	// NoEditorConfig ignores the .editorconfig files, whose end_of_line and
+	// charset otherwise apply to inserted headers
+	NoEditorConfig bool
+	// IndentBlocks indents the lines inside block comments without
+	// decoration, such as HTML's, as the .editorconfig files declare
+	IndentBlocks bool
+	// IncludeHidden processes dotfiles and the files of dot-directories,
+	// such as .github/workflows/ci.yml, which are skipped otherwise
+	IncludeHidden bool
+	// IncludeGenerated processes generated files, which carry a generated
+	// code marker or are marked linguist-generated in .gitattributes
+	IncludeGenerated bool
+	// ForceComment is the line comment prefix used for files with
+	// unrecognized extensions, which are skipped if it is empty
+	ForceComment string
+
+	// IgnorePatterns are gitignore-style patterns applied after those in
+	// .licensed-ignore
+	IgnorePatterns []string
+	// IncludePatterns are gitignore-style patterns restricting the run to
+	// the files they match, if any are given
+	IncludePatterns []string
+	// CommentSyntaxes override the comment syntax of file extensions
+	CommentSyntaxes map[string]CommentSyntax
+	// Overrides replace the license settings of parts of the project
+	Overrides []PathOverride
+
+	// CacheFile stores the size, modification time, hash and header status
+	// of the files checked, so that later runs skip the unchanged ones
+	CacheFile string
+
+	// DryRun writes unified diffs to Diff instead of modifying files
+	DryRun bool
+	// Diff receives the diffs of a dry run
+	Diff io.Writer
+	// Backup saves the originals of the files changed to BackupDir, from
+	// where Undo restores them
+	Backup bool
+	// ForceWritable modifies read-only files, granting write permission for
+	// the time of the write, instead of reporting them in Result.ReadOnly
+	ForceWritable bool
+
+	// Logger receives debug messages, warnings such as unknown extensions
+	// and per-file errors. Nothing is logged if it is nil.
+	Logger *slog.Logger
+	// Progress is called after each file to process with the number of
+	// files done and their total, e.g. to show a progress indicator on long
+	// runs. The files are then listed before any is processed.
+	Progress func(done, total int)
+
+	// Fix rewrites a header of the same license that is outdated, e.g. with
+	// an old company name or wording, in place rather than treating it as
+	// a different header
+	Fix bool
+
+	// OnConflict is the strategy for files with a different license
+	// header, ConflictAsk by default
+	OnConflict string
+	// PreserveNotice keeps the header replaced with ConflictReplace below
+	// the new one, introduced by OriginalNoticeLabel, as the attribution
+	// many licenses require of code taken from elsewhere
+	PreserveNotice bool
+	// Confirm decides with ConflictAsk whether a file with a different
+	// license header gets the new header above it, given the unified diff
+	// of the change. Such files are skipped if it is nil.
+	Confirm func(filePath, diff string) (bool, error)
+}
+
+// Result lists the files affected by a run
+type Result struct {
+	// Changed are the files modified, or that would be with DryRun
+	Changed []string
+	// Licensed are the files found to carry the license header already
+	Licensed []string
+	// Generated are the support files written, e.g. REUSE license texts
+	Generated []string
+	// Missing are the files lacking the license header, found by Check
+	Missing []string
+	// Conflicts are the files left untouched because they carry a different
+	// license header
+	Conflicts []string
+	// ReadOnly are the files left untouched because they are read-only or
+	// locked, see ForceWritable
+	ReadOnly []string
+	// Modified are the files whose header does not match its checksum
+	// marker, found by Check with Strict
+	Modified []string
+	// Skipped are the files left untouched, by reason
+	Skipped map[string][]string
+	// Lines holds the line the header of a Missing file belongs on, or the
+	// header of a Conflicts or Modified file starts on
+	Lines map[string]int
+	// Problems are the REUSE compliance problems found by Check
+	Problems []Problem
+	// Violations are the breaches of the license policy found by Check
+	Violations []Problem
+	// Detections are the licenses found by Detect
+	Detections []Detection
+	// Errors are the files that could not be processed, the run going on
+	// with the other files
+	Errors []Problem
+}
+
+// Problem is a REUSE compliance problem or an error with a file
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (r *Result) skip(reason, filePath string) {
+	if r.Skipped == nil {
+		r.Skipped = make(map[string][]string)
+	}
+	r.Skipped[reason] = append(r.Skipped[reason], filePath)
+}
+
+func (r *Result) line(filePath string, line int) {
+	if r.Lines == nil {
+		r.Lines = make(map[string]int)
+	}
+	r.Lines[filePath] = line
+}
+
+// Merge adds the outcome of another run to the result, e.g. to report on
+// several projects processed in one go
+func (r *Result) Merge(other *Result) {
+	r.Changed = append(r.Changed, other.Changed...)
+	r.Licensed = append(r.Licensed, other.Licensed...)
+	r.Generated = append(r.Generated, other.Generated...)
+	r.Missing = append(r.Missing, other.Missing...)
+	r.Conflicts = append(r.Conflicts, other.Conflicts...)
+	r.ReadOnly = append(r.ReadOnly, other.ReadOnly...)
+	r.Modified = append(r.Modified, other.Modified...)
+	for reason, files := range other.Skipped {
+		for _, filePath := range files {
+			r.skip(reason, filePath)
+		}
+	}
+	for filePath, line := range other.Lines {
+		r.line(filePath, line)
+	}
+	r.Problems = append(r.Problems, other.Problems...)
+	r.Violations = append(r.Violations, other.Violations...)
+	r.Detections = append(r.Detections, other.Detections...)
+	r.Errors = append(r.Errors, other.Errors...)
+}
+
+// Processor adds, checks, removes and updates license headers in the files
+// of a project
+type Processor struct {
+	opts            Options
+	commentSyntaxes map[string]CommentSyntax
+	ignoreRules     []ignoreRule
+	nestedIgnores   *ignoreTree
+	editorconfig    *editorconfigTree
+	includeRules    []ignoreRule
+	overrides       []pathOverride
+	policy          []policyRule
+	handlers        []fileHandler
+	gitattributes   *gitattributesTree
+	backups         *backupManifest
+	log             *slog.Logger
+}
+
+// New returns a Processor for opts, reading the .licensed-ignore and
+// comment-syntax.txt files of the project directory on top of the defaults
+func New(opts Options) (*Processor, error) {
+	if opts.Dir == "" {
+		opts.Dir = "."
+	}
+	if opts.Diff == nil {
+		opts.Diff = io.Discard
+	}
+	if _, err := licenseNames(opts.License); err != nil {
+		return nil, err
+	}
+	if opts.LicenseTextFile != "" && opts.License == "" && (opts.SPDX || opts.REUSE) && opts.Template == "" {
+		return nil, errors.New("SPDX headers of a license text file need a license identifier, e.g. LicenseRef-Proprietary")
+	}
+	if opts.CopyrightOnly && (opts.SPDX || opts.REUSE || opts.Template != "") {
+		return nil, errors.New("copyright-only headers cannot be combined with SPDX, REUSE or a template")
+	}
+	if opts.HeaderFile != "" && (opts.Template != "" || opts.SPDX || opts.REUSE || opts.CopyrightOnly) {
+		return nil, errors.New("a header file cannot be combined with a template, SPDX, REUSE or copyright-only headers")
+	}
+	switch opts.Position {
+	case "", PositionTop, PositionAfterDocstring:
+	default:
+		return nil, fmt.Errorf("unknown header position %q, expected %s or %s", opts.Position, PositionTop, PositionAfterDocstring)
+	}
+	switch opts.OnConflict {
+	case "", ConflictAsk, ConflictReplace, ConflictKeepBoth, ConflictSkip, ConflictFail:
+	default:
+		return nil, fmt.Errorf("unknown conflict strategy %q, expected %s", opts.OnConflict, strings.Join(ConflictStrategies, ", "))
+	}
+	p := &Processor{opts: opts, log: opts.Logger}
+	if p.log == nil {
+		p.log = discardLogger
+	}
+
+	// Parse the comment-syntax.txt mapping, letting project and option entries win
+	p.commentSyntaxes = ParseCommentSyntax(commentSyntaxFile)
+	projectCommentSyntaxFile, err := os.ReadFile(filepath.Join(opts.Dir, "comment-syntax.txt"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for ext, syntax := range ParseCommentSyntax(projectCommentSyntaxFile) {
+		p.commentSyntaxes[ext] = syntax
+	}
+	for ext, syntax := range opts.CommentSyntaxes {
+		// A syntax of only a width or banner restyles the default one
+		ext = strings.ToLower(ext)
+		if base, ok := p.commentSyntaxes[ext]; ok && !syntax.hasDelimiters() {
+			base.Width, base.Banner = syntax.Width, syntax.Banner
+			syntax = base
+		}
+		p.commentSyntaxes[ext] = syntax
+	}
+
+	// Parse the .licensed-ignore patterns, appending the project's so they
+	// can override the embedded ones, followed by the option patterns
+	p.ignoreRules = parseIgnoreRules(licensedIgnoreFile)
+	projectIgnoreFile, err := os.ReadFile(filepath.Join(opts.Dir, ".licensed-ignore"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	p.ignoreRules = append(p.ignoreRules, p.parsePatterns(projectIgnoreFile, ".licensed-ignore")...)
+	p.ignoreRules = append(p.ignoreRules, p.parsePatterns([]byte(strings.Join(anchorPatterns(opts.Dir, opts.IgnorePatterns), "\n")), "ignore patterns")...)
+	// The .licensed-ignore files of subdirectories are read during the
+	// walk, the project's is already among the rules
+	p.nestedIgnores = newIgnoreTree(opts.Dir, ".licensed-ignore")
+	p.nestedIgnores.rules["."] = nil
+	p.includeRules = p.parsePatterns([]byte(strings.Join(anchorPatterns(opts.Dir, opts.IncludePatterns), "\n")), "include patterns")
+	p.overrides = parsePathOverrides(opts.Overrides)
+	p.policy = parsePolicy(opts.Policy)
+	p.handlers = parseHandlers(opts.Handlers)
+	p.gitattributes = newGitattributesTree(opts.Dir)
+	if !opts.NoEditorConfig {
+		p.editorconfig = newEditorconfigTree()
+	}
+
+	return p, nil
+}
+
+// Add adds the license header to every file of the project missing it
+func (p *Processor) Add() (*Result, error) {
+	return p.stamp(false)
+}
+
+// Check reports the files of the project missing the license header
+// without modifying them
+func (p *Processor) Check() (*Result, error) {
+	return p.stamp(true)
+}
+
+func (p *Processor) stamp(checkOnly bool) (*Result, error) {
+	if !checkOnly {
+		unlock, err := p.lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	// Read the license or template content for the header
+	renderer, err := p.newHeaderRenderer(p.opts)
+	if err != nil {
+		return nil, err
+	}
+	renderers := map[int]*headerRenderer{-1: renderer}
+
+	// Fail early rather than on every file if the header misses values
+	sample, err := renderer.render("", p.opts.Year)
+	if errors.Is(err, ErrUnresolvedPlaceholder) {
+		return nil, err
+	}
+
+	// Load the cache of checked files, discarding it if the license or
+	// variables changed, including the values of template functions such as
+	// env that show in the sample header
+	var cache *licenseCache
+	if p.opts.CacheFile != "" {
+		cache = loadCache(p.opts.CacheFile, cacheKey(p.opts.License, ownerNames(p.opts.Owners), p.opts.Year, renderer.text, sample, strconv.FormatBool(p.opts.YearFromGit), strconv.FormatBool(p.opts.Checksum), strconv.FormatBool(p.opts.Strict), p.opts.Position, fmt.Sprint(p.placeholders()), fmt.Sprint(p.opts.Overrides)))
+	}
+
+	// Headers are classified against the catalog to enforce the policy
+	var known []knownLicense
+	if checkOnly && len(p.policy) > 0 {
+		if known, err = p.knownLicenses(); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &Result{}
+	licenses := make(map[string]bool)
+	err = p.forEachFile(result, func(filePath string) error {
+		// Note the licenses in use for the REUSE license texts
+		opts, _ := p.optionsFor(filePath)
+		licenses[opts.License] = true
+
+		// Enforce the policy on unchanged files as well
+		if known != nil {
+			if commentSyntax, ok := p.commentSyntaxFor(filePath); ok {
+				if err := p.checkPolicy(result, filePath, commentSyntax, known); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Files of a handler are up to its command
+		if handler := p.handlerFor(filePath); handler != nil {
+			renderer, err := p.rendererFor(renderers, filePath)
+			if err != nil {
+				return err
+			}
+			fileLicense, err := renderer.render(filePath, p.opts.Year)
+			if err != nil {
+				return err
+			}
+			return p.handleFile(result, handler, filePath, fileLicense, checkOnly)
+		}
+
+		// Skip files unchanged since they were last checked, reporting their
+		// missing header again when checking
+		if cache != nil {
+			if entry, ok := cache.lookup(filePath); ok {
+				switch {
+				case entry.Status == cacheLicensed:
+					result.Licensed = append(result.Licensed, filePath)
+					return nil
+				case entry.Status == cacheMissing && checkOnly:
+					result.Missing = append(result.Missing, filePath)
+					p.log.Warn("missing license header", "path", filePath)
+					result.line(filePath, entry.Line)
+					return nil
+				}
+			}
+		}
+
+		// Determine the comment syntax based on the file extension
+		commentSyntax, ok := p.commentSyntaxFor(filePath)
+		if !ok && !isNotebook(filePath) {
+			p.skipFile(result, "unknown type", filePath)
+			return nil
+		}
+
+		// Use the years the file was changed in according to git if requested
+		fileYear := p.opts.Year
+		if p.opts.YearFromGit {
+			fileYear = gitYearsOr(filePath, p.opts.Year)
+		}
+		renderer, err := p.rendererFor(renderers, filePath)
+		if err != nil {
+			return err
+		}
+		fileLicense, err := renderer.render(filePath, fileYear)
+		if err != nil {
+			return err
+		}
+
+		// Notebooks carry the header in a cell rather than atop their JSON
+		if isNotebook(filePath) {
+			return p.stampNotebook(result, filePath, fileLicense, checkOnly)
+		}
+		header := p.formatHeader(filePath, fileLicense, commentSyntax)
+
+		if checkOnly {
+			// Only report the file if the header is missing
+			head, err := readHead(filePath, headSize(fileLicense))
+			if err != nil {
+				return err
+			}
+			if p.opts.Strict {
+				// Catch headers edited or truncated by hand, which may no
+				// longer pass for the expected header either
+				if ok, line := verifyChecksum(head, filePath, commentSyntax); !ok && line > 0 {
+					result.Modified = append(result.Modified, filePath)
+					p.log.Warn("modified license header", "path", filePath, "line", line)
+					result.line(filePath, line)
+					return nil
+				}
+			}
+			if !p.hasLicenseHeader(head, header, filePath) {
+				line := p.preambleLength(strings.Split(head, "\n"), filePath) + 1
+				result.Missing = append(result.Missing, filePath)
+				p.log.Warn("missing license header", "path", filePath)
+				result.line(filePath, line)
+				if cache != nil {
+					cache.store(filePath, cacheEntry{Status: cacheMissing, Line: line})
+				}
+				return nil
+			}
+			result.Licensed = append(result.Licensed, filePath)
+		} else {
+			// Add the modified license header to each file
+			changed, conflictLine, err := p.addLicenseHeader(filePath, fileLicense, commentSyntax)
+			if err != nil {
+				return err
+			}
+			if conflictLine > 0 {
+				result.Conflicts = append(result.Conflicts, filePath)
+				p.log.Warn("different license header", "path", filePath, "line", conflictLine)
+				result.line(filePath, conflictLine)
+				return nil
+			}
+			if changed {
+				result.Changed = append(result.Changed, filePath)
+			} else {
+				result.Licensed = append(result.Licensed, filePath)
+			}
+		}
+
+		// Remember the file if it now carries the license header
+		if cache != nil {
+			cache.record(filePath, header, p.hasLicenseHeader)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// Provide the license texts and cover the files without a header
+	if p.opts.REUSE {
+		if err := p.reuse(result, licenses, checkOnly); err != nil {
+			return result, err
+		}
+	}
+
+	// Persist the cache for the next run
+	if cache != nil && !p.opts.DryRun {
+		if err := cache.save(p.opts.CacheFile); err != nil {
+			return result, fmt.Errorf("writing cache %s: %w", p.opts.CacheFile, err)
+		}
+	}
+	return result, nil
+}
+
+// AddLicenseHeader adds the license header to the file unless it already has
+// it, reporting whether the file was changed
+func (p *Processor) AddLicenseHeader(filePath, licenseContent string, commentSyntax CommentSyntax) (bool, error) {
+	changed, _, err := p.addLicenseHeader(filePath, licenseContent, commentSyntax)
+	return changed, err
+}
+
+// addLicenseHeader is AddLicenseHeader, also returning the line of the
+// different license header that was kept, or 0
+func (p *Processor) addLicenseHeader(filePath, licenseContent string, commentSyntax CommentSyntax) (bool, int, error) {
+	// Read the top of the file, which holds any existing header
+	head, err := readHead(filePath, headSize(licenseContent))
+	if err != nil {
+		return false, 0, err
+	}
+
+	newHead, conflictLine, err := p.insertHeader(head, filePath, licenseContent, commentSyntax)
+	if err != nil || conflictLine > 0 || newHead == head {
+		return false, conflictLine, err
+	}
+	return true, 0, p.writeHead(filePath, head, newHead)
+}
+
+// headSize is the number of bytes read from the top of a file to look for a
+// header of licenseContent, leaving room for comment markers, rewrapping and
+// a preamble
+func headSize(licenseContent string) int {
+	return 2*len(licenseContent) + 8<<10
+}
+
+// readHead returns the first size bytes of filePath, or the whole file if it
+// is shorter, as UTF-8 text. The head may end in the middle of a line.
+func readHead(filePath string, size int) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, size)
+	n, err := io.ReadFull(f, head)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// UTF-16 takes twice the bytes for the same text
+	if n == size && isUTF16(sniffEncoding(head)) {
+		more := make([]byte, size)
+		m, err := io.ReadFull(f, more)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+		head = append(head, more[:m]...)
+		n += m
+	}
+	text, _, err := decodeText(head[:n], true)
+	return text, err
+}
+
+// Strategies for files with a different license header
+const (
+	// ConflictAsk lets Confirm decide whether the new header goes above
+	// the existing one
+	ConflictAsk = "ask"
+	// ConflictReplace strips the existing header and adds the new one
+	ConflictReplace = "replace"
+	// ConflictKeepBoth adds the new header above the existing one
+	ConflictKeepBoth = "keep-both"
+	// ConflictSkip leaves the file as it is
+	ConflictSkip = "skip"
+	// ConflictFail leaves the file as it is too, the caller failing the run
+	// for the conflicts reported
+	ConflictFail = "fail"
+)
+
+// OriginalNoticeLabel introduces the header kept with PreserveNotice
+const OriginalNoticeLabel = "The original license notice of this file follows."
+
+// ConflictStrategies lists the values of OnConflict
+var ConflictStrategies = []string{ConflictAsk, ConflictReplace, ConflictKeepBoth, ConflictSkip, ConflictFail}
+
+// insertHeader returns content with the license header prepended, or
+// unchanged if it already has it. If content has a different header that
+// the OnConflict strategy leaves in place, it is returned unchanged along
+// with the line that header starts on.
+func (p *Processor) insertHeader(content, filePath, licenseContent string, commentSyntax CommentSyntax) (string, int, error) {
+	// If the header already exists, leave the file and its separator lines untouched
+	header := p.formatHeader(filePath, licenseContent, commentSyntax)
+	if p.hasLicenseHeader(content, header, filePath) {
+		return content, 0, nil
+	}
+
+	// Write the header in the line ending style declared by .editorconfig
+	// or else the file's, keeping its byte order mark first or adding one
+	// for charset = utf-8-bom; every other byte of the file is kept as is
+	bom, text := splitBOM(content)
+	config := p.editorconfigFor(filePath)
+	eol := config.eol(lineEnding(text))
+	cr := strings.TrimSuffix(eol, "\n")
+	if config.charset == "utf-8-bom" {
+		bom = utf8BOM
+	}
+
+	// Split the content into lines, keeping shebangs and similar preambles on top
+	lines := strings.Split(text, "\n")
+	preamble := p.preambleLength(lines, filePath)
+
+	// Prepend the license header
+	var newLines []string
+	newLines = append(newLines, lines[:preamble]...)
+	newLines = append(newLines, withLineEnding(header, eol)+cr)
+	for i := 0; i < p.opts.BlankLines; i++ {
+		newLines = append(newLines, cr)
+	}
+	newLines = append(newLines, lines[preamble:]...)
+
+	// Join the lines back into content
+	newContent := bom + strings.Join(newLines, "\n")
+
+	// Rewrite an outdated header of the same license in place
+	rest := strings.Join(lines[preamble:], "\n")
+	end := leadingCommentLength(rest, commentSyntax)
+	if p.opts.Fix {
+		opts, _ := p.optionsFor(filePath)
+		if end > 0 && sameLicense(rest[:end], licenseContent, opts.License) {
+			stripped, _ := stripLicenseHeader(rest, "", commentSyntax)
+			newLines = append(newLines[:preamble+1+p.opts.BlankLines], stripped)
+			p.log.Debug("replacing outdated license header", "path", filePath)
+			return bom + strings.Join(newLines, "\n"), 0, nil
+		}
+	}
+
+	// If the comment block leading the file is a different license header,
+	// follow the conflict strategy
+	if end == 0 || !looksLikeLicense(rest[:end]) {
+		return newContent, 0, nil
+	}
+	switch p.opts.OnConflict {
+	case ConflictReplace:
+		if p.opts.PreserveNotice {
+			label := withLineEnding(p.formatHeader(filePath, OriginalNoticeLabel, commentSyntax), eol) + cr
+			newLines = append(newLines[:preamble+1+p.opts.BlankLines], label, rest)
+			p.log.Debug("keeping different license header as the original notice", "path", filePath)
+			return bom + strings.Join(newLines, "\n"), 0, nil
+		}
+		stripped, _ := stripLicenseHeader(rest, "", commentSyntax)
+		newLines = append(newLines[:preamble+1+p.opts.BlankLines], stripped)
+		p.log.Debug("replacing different license header", "path", filePath)
+		return bom + strings.Join(newLines, "\n"), 0, nil
+	case ConflictKeepBoth:
+	case ConflictSkip, ConflictFail:
+		return content, preamble + 1, nil
+	default:
+		add := false
+		if p.opts.Confirm != nil {
+			var err error
+			add, err = p.opts.Confirm(filePath, unifiedDiff(filePath, content, newContent))
+			if err != nil {
+				return content, 0, err
+			}
+		}
+		if !add {
+			return content, preamble + 1, nil
+		}
+	}
+	return newContent, 0, nil
+}
+
+// writeFile replaces the content of filePath, or writes a diff of the change
+// in a dry run. With Backup the original is saved first.
+func (p *Processor) writeFile(filePath, oldContent, newContent string) error {
+	if p.opts.DryRun {
+		_, err := io.WriteString(p.opts.Diff, unifiedDiff(filePath, oldContent, newContent))
+		return err
+	}
+	if oldContent == newContent {
+		return nil
+	}
+	restore, err := p.makeWritable(filePath)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	if p.opts.Backup {
+		if err := p.backupFile(filePath); err != nil {
+			return fmt.Errorf("backing up %s: %w", filePath, err)
+		}
+	}
+
+	// Write the file back in its encoding, leaving it alone if it changed
+	// since it was read
+	encoding, err := fileEncoding(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return writeError(atomicWriteStream(filePath, 0644, unchangedSince(string(encodeText(oldContent, encoding))), func(w io.Writer) error {
+		_, err := w.Write(encodeText(newContent, encoding))
+		return err
+	}))
+}
+
+// writeHead is writeFile for a file whose first len(oldHead) bytes are
+// replaced by newHead, copying the rest of the file without loading it
+func (p *Processor) writeHead(filePath, oldHead, newHead string) error {
+	if p.opts.DryRun {
+		_, err := io.WriteString(p.opts.Diff, unifiedDiff(filePath, oldHead, newHead))
+		return err
+	}
+	restore, err := p.makeWritable(filePath)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	if p.opts.Backup {
+		if err := p.backupFile(filePath); err != nil {
+			return fmt.Errorf("backing up %s: %w", filePath, err)
+		}
+	}
+
+	// The rest of the file is copied as it is at the time of writing, the
+	// head written back in the encoding of the file. The file is left alone
+	// if its head changed since it was read, or if it changes while being
+	// copied.
+	encoding, err := fileEncoding(filePath)
+	if err != nil {
+		return err
+	}
+	oldBytes, newBytes := encodeText(oldHead, encoding), encodeText(newHead, encoding)
+	var copied os.FileInfo
+	verify := func(path string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().Equal(copied.ModTime()) || info.Size() != copied.Size() {
+			return ErrFileChanged
+		}
+		return nil
+	}
+	return writeError(atomicWriteStream(filePath, 0644, verify, func(w io.Writer) error {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if copied, err = f.Stat(); err != nil {
+			return err
+		}
+		head := make([]byte, len(oldBytes))
+		if _, err := io.ReadFull(f, head); err != nil || !bytes.Equal(head, oldBytes) {
+			return ErrFileChanged
+		}
+		if _, err := w.Write(newBytes); err != nil {
+			return err
+		}
+		_, err = io.Copy(w, f)
+		return err
+	}))
+}
+
+// forEachFile calls fn for every file to process: Files if given, the
+// staged files with StagedOnly, the changed files with ChangedSince,
+// otherwise every file under the project directory. Ignored, cache, binary and generated files
+// are filtered out beforehand. Files that cannot be read or that fn fails
+// on are reported in the Errors of result, and the walk goes on.
+func (p *Processor) forEachFile(result *Result, fn func(filePath string) error) error {
+	// Files reachable through several paths are only processed once
+	seenFiles := make(map[any]bool)
+	process := func(filePath string) error {
+		// Check if the file should be ignored
+		if p.isCacheFile(filePath) || p.isLockFile(filePath) {
+			return nil
+		}
+		if p.shouldIgnoreFile(filePath) || !p.isIncluded(filePath) {
+			p.skipFile(result, "ignored", filePath)
+			return nil
+		}
+		if !p.opts.IncludeHidden && p.isHidden(filePath) {
+			p.skipFile(result, "hidden", filePath)
+			return nil
+		}
+		if !visitOnce(seenFiles, filePath) {
+			p.skipFile(result, "duplicate", filePath)
+			return nil
+		}
+
+		// Leave huge files alone rather than loading them into memory
+		if p.opts.MaxFileSize > 0 {
+			info, err := os.Stat(filePath)
+			if err != nil {
+				p.fileError(result, filePath, err)
+				return nil
+			}
+			if info.Size() > p.opts.MaxFileSize {
+				p.skipFile(result, "too large", filePath)
+				return nil
+			}
+		}
+
+		// Leave vendored and export-ignore files to their owners
+		if reason := p.gitattributes.skipReason(filePath); reason != "" {
+			p.skipFile(result, reason, filePath)
+			return nil
+		}
+
+		// Skip encodings the file cannot be written back in. UTF-16 is
+		// transcoded, the NUL bytes of its text not making it binary.
+		// Handlers take any file their command can deal with.
+		if p.handlerFor(filePath) == nil {
+			problem, err := checkEncoding(filePath)
+			if err != nil {
+				p.fileError(result, filePath, err)
+				return nil
+			}
+			if problem != "" {
+				p.log.Warn("unsupported encoding", "path", filePath, "reason", problem)
+				p.skipFile(result, "unsupported encoding", filePath)
+				return nil
+			}
+
+			// Never touch binary files
+			binary, err := isBinaryFile(filePath)
+			if err != nil {
+				p.fileError(result, filePath, err)
+				return nil
+			}
+			if binary {
+				p.skipFile(result, "binary", filePath)
+				return nil
+			}
+		}
+
+		// Leave generated files to their generator
+		if !p.opts.IncludeGenerated {
+			generated, err := p.isGeneratedFile(filePath)
+			if err != nil {
+				p.fileError(result, filePath, err)
+				return nil
+			}
+			if generated {
+				p.skipFile(result, "generated", filePath)
+				return nil
+			}
+		}
+		p.log.Debug("processing file", "path", filePath)
+		if err := fn(filePath); err != nil {
+			p.fileError(result, filePath, err)
+		}
+		return nil
+	}
+	if p.opts.Progress == nil {
+		return p.listFiles(result, process)
+	}
+
+	// List the files first to know how many there are
+	var files []string
+	err := p.listFiles(result, func(filePath string) error {
+		files = append(files, filePath)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i, filePath := range files {
+		if err := process(filePath); err != nil {
+			return err
+		}
+		p.opts.Progress(i+1, len(files))
+	}
+	return nil
+}
+
+// listFiles calls visit for Files if given, the staged files with
+// StagedOnly, the changed files with ChangedSince, otherwise every file
+// under the project directory that git and the default skipped directories
+// do not rule out
+func (p *Processor) listFiles(result *Result, visit func(filePath string) error) error {
+	if len(p.opts.Files) > 0 || p.opts.StagedOnly || p.opts.ChangedSince != "" {
+		// Only process the listed files, or those staged for commit or
+		// changed since the ref
+		files := p.opts.Files
+		var err error
+		switch {
+		case len(files) > 0:
+		case p.opts.StagedOnly:
+			files, err = stagedFiles(p.opts.Dir)
+		default:
+			files, err = changedFiles(p.opts.Dir, p.opts.ChangedSince)
+		}
+		if err != nil {
+			return fmt.Errorf("listing changed files: %w", err)
+		}
+		for _, filePath := range files {
+			if err := visit(filePath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// git knows the tracked files, outside a work tree the directory is
+	// walked instead
+	if p.opts.TrackedOnly {
+		files, err := trackedFiles(p.opts.Dir)
+		if err == nil {
+			return p.listTrackedFiles(result, files, visit)
+		}
+		p.log.Debug("walking the directory instead of listing tracked files", "error", err)
+	}
+
+	// Recursively traverse the project directory, skipping VCS, dependency
+	// and build directories as well as anything ignored by git. Directories
+	// are entered once, so links cannot make the walk loop.
+	root := p.opts.Dir
+	gitignores := newIgnoreTree(root, ".gitignore")
+	seenDirs := make(map[any]bool)
+	var walk filepath.WalkFunc
+	walk = func(filePath string, info os.FileInfo, err error) error {
+		// Report unreadable files and directories below the root and go on
+		if err != nil {
+			if filePath == root {
+				return err
+			}
+			p.fileError(result, filePath, err)
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			// The project directory itself may be a link
+			if !p.opts.FollowSymlinks && filePath != root {
+				p.skipFile(result, "symlink", filePath)
+				return nil
+			}
+			target, err := os.Stat(filePath)
+			if err != nil {
+				p.fileError(result, filePath, err)
+				return nil
+			}
+			if target.IsDir() {
+				// Walk the linked directory under the path of the link
+				return filepath.Walk(filePath+string(filepath.Separator), walk)
+			}
+			info = target
+		}
+		if filePath != root && isReparsePoint(info) {
+			p.skipFile(result, "reparse point", filePath)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			if filePath != root && (p.isDefaultSkippedDir(p.relPath(filePath)) || (!p.opts.IncludeHidden && isHiddenName(info.Name())) || p.shouldSkipDir(filePath) || (!p.opts.NoGitignore && gitignores.ignored(filePath, true))) {
+				return filepath.SkipDir
+			}
+			if !visitOnce(seenDirs, filePath) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !p.opts.NoGitignore && gitignores.ignored(filePath, false) {
+			p.skipFile(result, "ignored", filePath)
+			return nil
+		}
+		return visit(filePath)
+	}
+	return filepath.Walk(root, walk)
+}
+
+// listTrackedFiles calls visit for the tracked files, skipping those the walk
+// of listFiles would: files in the default skipped and ignored directories,
+// links unless FollowSymlinks is set, and reparse points
+func (p *Processor) listTrackedFiles(result *Result, files []string, visit func(filePath string) error) error {
+	for _, filePath := range files {
+		rel, ok := slashRel(p.opts.Dir, filePath)
+		if !ok {
+			continue
+		}
+		skipped := false
+		dirs := strings.Split(rel, "/")
+		for i := range dirs[:len(dirs)-1] {
+			if p.isDefaultSkippedDir(strings.Join(dirs[:i+1], "/")) || p.shouldSkipDir(filepath.Join(p.opts.Dir, filepath.FromSlash(strings.Join(dirs[:i+1], "/")))) {
+				skipped = true
+				break
+			}
+		}
+		if skipped {
+			continue
+		}
+
+		// Files deleted but not yet staged are still listed
+		info, err := os.Lstat(filePath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			p.fileError(result, filePath, err)
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !p.opts.FollowSymlinks {
+				p.skipFile(result, "symlink", filePath)
+				continue
+			}
+			if info, err = os.Stat(filePath); err != nil {
+				p.fileError(result, filePath, err)
+				continue
+			}
+		}
+		if isReparsePoint(info) {
+			p.skipFile(result, "reparse point", filePath)
+			continue
+		}
+		// Submodules are listed as directories
+		if info.IsDir() {
+			continue
+		}
+		if err := visit(filePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commentSyntaxFor returns the comment syntax for a file, looked up by name
+// for well-known files such as Makefile, otherwise by extension, or by the
+// shebang line of scripts without one. Unknown files only get a comment
+// syntax if ForceComment is set.
+func (p *Processor) commentSyntaxFor(filePath string) (CommentSyntax, bool) {
+	name := strings.ToLower(filepath.Base(filePath))
+	if isDockerfile(filePath) {
+		name = "dockerfile"
+	}
+	if syntax, ok := p.commentSyntaxes[name]; ok {
+		return syntax, true
+	}
+	if syntax, ok := p.commentSyntaxes[strings.ToLower(filepath.Ext(filePath))]; ok {
+		return syntax, true
+	}
+
+	// Scripts without an extension are recognized by their interpreter
+	if filepath.Ext(filePath) == "" {
+		if syntax, ok := p.commentSyntaxes[shebangExtension(filePath)]; ok {
+			return syntax, true
+		}
+	}
+	if p.opts.ForceComment != "" {
+		return CommentSyntax{LinePrefix: p.opts.ForceComment}, true
+	}
+	return CommentSyntax{}, false
+}
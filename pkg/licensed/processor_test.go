@@ -0,0 +1,139 @@
+package licensed
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestProcessor returns a processor for the MIT license of x in 2024 on
+// a temporary project directory
+func newTestProcessor(t *testing.T, opts Options) *Processor {
+	t.Helper()
+	opts.License = "mit"
+	opts.Owners = []Owner{{Name: "x"}}
+	opts.Year = "2024"
+	if opts.Dir == "" {
+		opts.Dir = t.TempDir()
+	}
+	p, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestInsertHeaderBlankLines(t *testing.T) {
+	const header = "Copyright (c) 2024 x\nSPDX-License-Identifier: MIT"
+	tests := []struct {
+		blankLines int
+		filePath   string
+		content    string
+		want       string
+	}{
+		{
+			blankLines: 0,
+			filePath:   "a.go",
+			content:    "package a\n",
+			want:       "// Copyright (c) 2024 x\n// SPDX-License-Identifier: MIT\npackage a\n",
+		},
+		{
+			blankLines: 2,
+			filePath:   "a.go",
+			content:    "package a\n",
+			want:       "// Copyright (c) 2024 x\n// SPDX-License-Identifier: MIT\n\n\npackage a\n",
+		},
+		{
+			blankLines: 0,
+			filePath:   "a.sh",
+			content:    "#!/bin/sh\necho\n",
+			want:       "#!/bin/sh\n# Copyright (c) 2024 x\n# SPDX-License-Identifier: MIT\necho\n",
+		},
+		{
+			blankLines: 2,
+			filePath:   "a.sh",
+			content:    "#!/bin/sh\necho\n",
+			want:       "#!/bin/sh\n# Copyright (c) 2024 x\n# SPDX-License-Identifier: MIT\n\n\necho\n",
+		},
+	}
+	for _, tt := range tests {
+		p := newTestProcessor(t, Options{BlankLines: tt.blankLines})
+		syntax, ok := p.commentSyntaxFor(tt.filePath)
+		if !ok {
+			t.Fatalf("no comment syntax for %s", tt.filePath)
+		}
+
+		got, _, err := p.insertHeader(tt.content, tt.filePath, header, syntax)
+		if err != nil {
+			t.Fatalf("%s with %d blank lines: %v", tt.filePath, tt.blankLines, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s with %d blank lines:\ngot  %q\nwant %q", tt.filePath, tt.blankLines, got, tt.want)
+		}
+		if !p.hasLicenseHeader(got, header, tt.filePath) {
+			t.Errorf("%s with %d blank lines: header not found in %q", tt.filePath, tt.blankLines, got)
+		}
+		if p.hasLicenseHeader(tt.content, header, tt.filePath) {
+			t.Errorf("%s with %d blank lines: header found in %q", tt.filePath, tt.blankLines, tt.content)
+		}
+	}
+}
+
+func TestAddTwice(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":       "package a\n",
+		"run.sh":     "#!/bin/sh\necho\n",
+		"sub/b.py":   "# -*- coding: utf-8 -*-\nprint()\n",
+		"sub/c.c":    "/* c */\nint main(void) { return 0; }\n",
+		"sub/d.lua":  "print()",
+		"empty.java": "",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := newTestProcessor(t, Options{Dir: dir})
+	result, err := p.Add()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changed) != len(files) {
+		t.Fatalf("first run changed %v, want all %d files", result.Changed, len(files))
+	}
+	stamped := make(map[string][]byte)
+	for name := range files {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stamped[name] = content
+	}
+
+	result, err = p.Add()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changed) != 0 {
+		t.Errorf("second run changed %v", result.Changed)
+	}
+	if len(result.Licensed) != len(files) {
+		t.Errorf("second run found %v licensed, want all %d files", result.Licensed, len(files))
+	}
+	for name, want := range stamped {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("second run rewrote %s:\ngot  %q\nwant %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package licensed
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Remove strips the license header from every file of the project. With
+// License or Template set, also through Overrides, the exact rendered header
+// is removed as well as leading comments that read like a license notice.
+func (p *Processor) Remove() (*Result, error) {
+	unlock, err := p.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	renderers := make(map[int]*headerRenderer)
+
+	result := &Result{}
+	err = p.forEachFile(result, func(filePath string) error {
+		handler := p.handlerFor(filePath)
+		commentSyntax, ok := p.commentSyntaxFor(filePath)
+		if !ok && handler == nil {
+			p.skipFile(result, "unknown type", filePath)
+			return nil
+		}
+
+		renderer, err := p.rendererFor(renderers, filePath)
+		if err != nil {
+			return err
+		}
+		var licenseContent string
+		if renderer != nil {
+			// Without the owner or other values of the header, only the
+			// comments reading like a license notice are removed
+			content, err := renderer.render(filePath, p.opts.Year)
+			if err != nil && !errors.Is(err, ErrUnresolvedPlaceholder) {
+				return err
+			}
+			licenseContent = content
+		}
+
+		var removed bool
+		if handler != nil {
+			removed, err = p.removeWithHandler(handler, filePath, licenseContent)
+		} else {
+			removed, err = p.RemoveLicenseHeader(filePath, licenseContent, commentSyntax)
+		}
+		if err != nil {
+			return err
+		}
+		if removed {
+			result.Changed = append(result.Changed, filePath)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// RemoveLicenseHeader deletes the license header at the top of the file,
+// reporting whether one was found
+func (p *Processor) RemoveLicenseHeader(filePath, licenseContent string, commentSyntax CommentSyntax) (bool, error) {
+	// Read the existing file content
+	content, err := readText(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	// Keep the byte order mark, shebangs and similar preambles in place
+	bom, text := splitBOM(content)
+	preamble, rest := splitPreamble(text, filePath)
+	var header string
+	if licenseContent != "" {
+		header = withLineEnding(p.formatHeader(filePath, licenseContent, commentSyntax), lineEnding(text))
+	}
+	rest, removed := stripLicenseHeader(rest, header, commentSyntax)
+	if !removed {
+		// The header may follow a file-level docstring
+		doc, afterDoc := splitDocstring(rest, filePath)
+		if afterDoc, removed = stripLicenseHeader(afterDoc, header, commentSyntax); !removed {
+			return false, nil
+		}
+		rest = doc + afterDoc
+	}
+	return true, p.writeFile(filePath, content, bom+preamble+rest)
+}
+
+// stripLicenseHeader removes the leading comment block of content if it is
+// the rendered header or a license notice, along with the blank lines
+// separating it from the code
+func stripLicenseHeader(content, header string, commentSyntax CommentSyntax) (string, bool) {
+	var rest string
+	if header != "" && strings.HasPrefix(content, header) {
+		rest = strings.TrimPrefix(content, header)
+	} else {
+		end := leadingCommentLength(content, commentSyntax)
+		if end == 0 || !looksLikeLicense(content[:end]) {
+			return content, false
+		}
+		rest = content[end:]
+	}
+
+	// Drop the separator between the header and the code
+	return strings.TrimLeft(rest, "\r\n"), true
+}
+
+// leadingCommentLength returns the length in bytes of the comment block at
+// the start of content: a single block comment or consecutive line
+// comments. The block form is tried first since its opening may start with
+// the line prefix, as Lua's --[[ does.
+func leadingCommentLength(content string, commentSyntax CommentSyntax) int {
+	if commentSyntax.BlockOpen != "" && strings.HasPrefix(content, commentSyntax.BlockOpen) {
+		// Consume up to the end of the block comment
+		if end := strings.Index(content[len(commentSyntax.BlockOpen):], commentSyntax.BlockClose); end >= 0 {
+			return end + len(commentSyntax.BlockOpen) + len(commentSyntax.BlockClose)
+		}
+	}
+	if commentSyntax.LinePrefix != "" && strings.HasPrefix(content, commentSyntax.LinePrefix) {
+		// Consume consecutive line comments
+		var end int
+		for _, line := range strings.SplitAfter(content, "\n") {
+			if !strings.HasPrefix(strings.TrimSpace(line), commentSyntax.LinePrefix) {
+				break
+			}
+			end += len(line)
+		}
+		return end
+	}
+	return 0
+}
+
+// copyrightNotice matches a copyright notice with its year, e.g.
+// Copyright (c) 2024, Copyright 2024 or © 2024
+var copyrightNotice = regexp.MustCompile(`(?i)(?:\bcopyright\b|©|\(c\))[ \t]*(?:\(c\)|©)?[ \t]*(?:19|20)\d{2}\b`)
+
+// bundledLicenses are the texts and headers of the bundled catalog broken
+// into word pairs, loaded on first use
+var bundledLicenses = sync.OnceValue(func() []knownLicense {
+	known, _ := loadKnownLicenses("")
+	return known
+})
+
+// looksLikeLicense reports whether a comment block is a license notice: it
+// carries a copyright notice with a year or an SPDX tag, or reads like a
+// license text or header of the catalog. A comment merely mentioning a
+// license, such as the doc comment of a package parsing them, is none.
+func looksLikeLicense(comment string) bool {
+	if copyrightNotice.MatchString(comment) || spdxTag.MatchString(comment) || strings.Contains(comment, "SPDX-FileCopyrightText:") {
+		return true
+	}
+	pairs := wordPairs(comment)
+	for _, license := range bundledLicenses() {
+		if diceCoefficient(pairs, license.pairs) >= minSimilarity {
+			return true
+		}
+	}
+	return false
+}
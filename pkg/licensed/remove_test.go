@@ -0,0 +1,62 @@
+package licensed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoveKeepsDocComment(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]struct{ content, want string }{
+		"doc.go": {
+			content: "// Package licenses parses license files.\npackage licenses\n",
+			want:    "// Package licenses parses license files.\npackage licenses\n",
+		},
+		"other.go": {
+			content: "// Copyright (c) 2019 Other Corp\n// All rights reserved.\n\npackage other\n",
+			want:    "package other\n",
+		},
+		"spdx.go": {
+			content: "// SPDX-License-Identifier: Apache-2.0\n\npackage spdx\n",
+			want:    "package spdx\n",
+		},
+	}
+	for name, file := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(file.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := newTestProcessor(t, Options{Dir: dir})
+	if _, err := p.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	for name, file := range files {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != file.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", name, got, file.want)
+		}
+	}
+}
+
+func TestInsertHeaderReplaceKeepsDocComment(t *testing.T) {
+	const doc = "// Package licenses parses license files.\npackage licenses\n"
+	p := newTestProcessor(t, Options{OnConflict: ConflictReplace})
+	syntax, _ := p.commentSyntaxFor("doc.go")
+
+	got, conflict, err := p.insertHeader(doc, "doc.go", "Copyright (c) 2024 x", syntax)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conflict != 0 {
+		t.Errorf("doc comment reported as a conflicting header on line %d", conflict)
+	}
+	if !strings.HasSuffix(got, "\n"+doc) {
+		t.Errorf("doc comment replaced:\n%s", got)
+	}
+}
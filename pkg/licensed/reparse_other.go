@@ -0,0 +1,10 @@
+//go:build !windows
+
+package licensed
+
+import "os"
+
+// isReparsePoint is always false on systems without reparse points
+func isReparsePoint(info os.FileInfo) bool {
+	return false
+}
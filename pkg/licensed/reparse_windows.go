@@ -0,0 +1,19 @@
+//go:build windows
+
+package licensed
+
+import (
+	"os"
+	"syscall"
+)
+
+// isReparsePoint reports whether info describes a reparse point other than a
+// symbolic link, such as a junction to %AppData% or a OneDrive placeholder.
+// The walk never enters them.
+func isReparsePoint(info os.FileInfo) bool {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return false
+	}
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	return ok && data.FileAttributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT != 0
+}
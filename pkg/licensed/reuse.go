@@ -0,0 +1,220 @@
+package licensed
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// dep5Path is the file covering the files without a header in REUSE mode,
+// relative to the project directory
+const dep5Path = ".reuse/dep5"
+
+// dep5Header starts a new .reuse/dep5 file, followed by the project name
+const dep5Header = "Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/\nUpstream-Name: "
+
+// reuse makes the project REUSE compliant after its files got their
+// headers: it writes the LICENSES/ texts of the licenses in use and covers
+// the binary and unknown files in .reuse/dep5. With checkOnly the problems
+// are reported in result instead.
+func (p *Processor) reuse(result *Result, licenses map[string]bool, checkOnly bool) error {
+	// Files that cannot carry a header must be covered by .reuse/dep5
+	var uncovered []string
+	dep5, err := os.ReadFile(filepath.Join(p.opts.Dir, dep5Path))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	patterns := dep5Patterns(string(dep5))
+	for _, reason := range []string{"binary", "unknown type"} {
+		for _, filePath := range result.Skipped[reason] {
+			opts, _ := p.optionsFor(filePath)
+			licenses[opts.License] = true
+			if !dep5Covers(patterns, p.relPath(filePath)) {
+				uncovered = append(uncovered, filePath)
+			}
+		}
+	}
+
+	if checkOnly {
+		for _, filePath := range uncovered {
+			p.problem(result, filePath, "no copyright and licensing information")
+		}
+	} else if len(uncovered) > 0 {
+		if err := p.writeDep5(result, string(dep5), uncovered); err != nil {
+			return err
+		}
+	}
+
+	return p.reuseLicenseTexts(result, licenses, checkOnly)
+}
+
+// reuseLicenseTexts writes the missing LICENSES/<id>.txt texts of licenses.
+// With checkOnly, missing and unused texts are reported in result instead.
+func (p *Processor) reuseLicenseTexts(result *Result, licenses map[string]bool, checkOnly bool) error {
+	dir := filepath.Join(p.opts.Dir, "LICENSES")
+	ids := make(map[string]bool)
+
+	// Every license of a license expression needs its text
+	var names []string
+	for expression := range licenses {
+		components, err := licenseNames(expression)
+		if err != nil {
+			return err
+		}
+		names = append(names, components...)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		id := SPDXID(name)
+		if ids[id] {
+			continue
+		}
+		ids[id] = true
+		path := filepath.Join(dir, id+".txt")
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+
+		if checkOnly {
+			p.problem(result, path, "missing license text for "+id)
+			continue
+		}
+		text, err := p.readLicense(name)
+		if err != nil {
+			return err
+		}
+		if !p.opts.DryRun {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		filled, err := p.placeholders().Fill(string(text))
+		if err != nil {
+			return err
+		}
+		if err := p.writeFile(path, "", filled); err != nil {
+			return err
+		}
+		result.Generated = append(result.Generated, path)
+	}
+
+	// Report license texts no file refers to
+	if checkOnly {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, entry := range entries {
+			id := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			if !entry.IsDir() && !ids[id] {
+				p.problem(result, filepath.Join(dir, entry.Name()), "unused license text")
+			}
+		}
+	}
+	return nil
+}
+
+// writeDep5 appends stanzas covering files to the .reuse/dep5 content,
+// one per license and copyright holders in use
+func (p *Processor) writeDep5(result *Result, content string, files []string) error {
+	// A new file starts with the header, compared against no content when
+	// written
+	old := content
+	if content == "" {
+		project, err := filepath.Abs(p.opts.Dir)
+		if err != nil {
+			return err
+		}
+		content = dep5Header + filepath.Base(project) + "\n"
+	}
+
+	// Group the files by the path override their license comes from
+	groups := make(map[int][]string)
+	var order []int
+	for _, filePath := range files {
+		_, index := p.optionsFor(filePath)
+		if _, ok := groups[index]; !ok {
+			order = append(order, index)
+		}
+		groups[index] = append(groups[index], filePath)
+	}
+
+	newContent := content
+	for _, index := range order {
+		opts, _ := p.optionsFor(groups[index][0])
+		var rels []string
+		for _, filePath := range groups[index] {
+			rels = append(rels, p.relPath(filePath))
+		}
+
+		var copyrights []string
+		for _, owner := range opts.Owners {
+			year := owner.Year
+			if year == "" {
+				year = opts.Year
+			}
+			copyrights = append(copyrights, year+" "+owner.Name)
+		}
+		newContent += "\nFiles: " + strings.Join(rels, "\n ") + "\n" +
+			"Copyright: " + strings.Join(copyrights, "\n ") + "\n" +
+			"License: " + SPDXID(opts.License) + "\n"
+	}
+
+	path := filepath.Join(p.opts.Dir, dep5Path)
+	if !p.opts.DryRun {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+	}
+	if err := p.writeFile(path, old, newContent); err != nil {
+		return err
+	}
+	result.Generated = append(result.Generated, path)
+	return nil
+}
+
+// dep5Patterns returns the patterns of the Files fields of a .reuse/dep5
+// file, including their continuation lines
+func dep5Patterns(content string) []string {
+	var patterns []string
+	var inFiles bool
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, "Files:"):
+			inFiles = true
+			patterns = append(patterns, strings.Fields(strings.TrimPrefix(line, "Files:"))...)
+		case inFiles && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")):
+			patterns = append(patterns, strings.Fields(line)...)
+		default:
+			inFiles = false
+		}
+	}
+	return patterns
+}
+
+// dep5Covers reports whether one of the dep5 patterns matches the slash
+// separated rel path. As in debian/copyright, * and ? also match slashes.
+func dep5Covers(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		var b strings.Builder
+		b.WriteString("^")
+		for _, c := range pattern {
+			switch c {
+			case '*':
+				b.WriteString(".*")
+			case '?':
+				b.WriteString(".")
+			default:
+				b.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		}
+		b.WriteString("$")
+		if regexp.MustCompile(b.String()).MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
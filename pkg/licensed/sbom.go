@@ -0,0 +1,194 @@
+package licensed
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SPDXDocument is an SPDX 2.3 document in its JSON serialization,
+// describing the project as one package made of its files
+type SPDXDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      SPDXCreationInfo   `json:"creationInfo"`
+	Packages          []SPDXPackage      `json:"packages"`
+	Files             []SPDXFile         `json:"files"`
+	Relationships     []SPDXRelationship `json:"relationships"`
+}
+
+// SPDXCreationInfo tells who created an SPDX document and when
+type SPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+// SPDXPackage is the project in an SPDX document
+type SPDXPackage struct {
+	Name                 string               `json:"name"`
+	SPDXID               string               `json:"SPDXID"`
+	Supplier             string               `json:"supplier,omitempty"`
+	DownloadLocation     string               `json:"downloadLocation"`
+	FilesAnalyzed        bool                 `json:"filesAnalyzed"`
+	VerificationCode     SPDXVerificationCode `json:"packageVerificationCode"`
+	LicenseConcluded     string               `json:"licenseConcluded"`
+	LicenseDeclared      string               `json:"licenseDeclared"`
+	LicenseInfoFromFiles []string             `json:"licenseInfoFromFiles"`
+	CopyrightText        string               `json:"copyrightText"`
+}
+
+// SPDXVerificationCode is the SHA-1 of the sorted SHA-1s of the files of a
+// package
+type SPDXVerificationCode struct {
+	Value string `json:"packageVerificationCodeValue"`
+}
+
+// SPDXFile is a file of the project in an SPDX document, with the licenses
+// and copyright notices of its header
+type SPDXFile struct {
+	FileName           string         `json:"fileName"`
+	SPDXID             string         `json:"SPDXID"`
+	Checksums          []SPDXChecksum `json:"checksums"`
+	LicenseConcluded   string         `json:"licenseConcluded"`
+	LicenseInfoInFiles []string       `json:"licenseInfoInFiles"`
+	CopyrightText      string         `json:"copyrightText"`
+}
+
+// SPDXChecksum is a checksum of a file
+type SPDXChecksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"checksumValue"`
+}
+
+// SPDXRelationship relates two elements of an SPDX document
+type SPDXRelationship struct {
+	Element string `json:"spdxElementId"`
+	Type    string `json:"relationshipType"`
+	Related string `json:"relatedSpdxElement"`
+}
+
+// SBOM describes the project as an SPDX document: every file with a known
+// comment syntax, as found by Detect, with its checksums, the licenses of
+// its header and its copyright notices. The license of the project is the
+// declared license of the package.
+func (p *Processor) SBOM() (*SPDXDocument, error) {
+	result, err := p.Detect()
+	if err != nil {
+		return nil, err
+	}
+	namespace := make([]byte, 16)
+	if _, err := rand.Read(namespace); err != nil {
+		return nil, err
+	}
+
+	values := p.placeholders()
+	doc := &SPDXDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              values.Project,
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/%s-%x", strings.ReplaceAll(values.Project, " ", "-"), namespace),
+		CreationInfo: SPDXCreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: licensed"},
+		},
+		Files: []SPDXFile{},
+	}
+	pkg := SPDXPackage{
+		Name:             values.Project,
+		SPDXID:           "SPDXRef-Package",
+		DownloadLocation: "NOASSERTION",
+		FilesAnalyzed:    true,
+		LicenseConcluded: "NOASSERTION",
+		LicenseDeclared:  "NOASSERTION",
+		CopyrightText:    "NOASSERTION",
+	}
+	if p.opts.Organization != "" {
+		doc.CreationInfo.Creators = append(doc.CreationInfo.Creators, "Organization: "+p.opts.Organization)
+		pkg.Supplier = "Organization: " + p.opts.Organization
+	}
+	if p.opts.URL != "" {
+		pkg.DownloadLocation = p.opts.URL
+	}
+	if p.opts.License != "" {
+		pkg.LicenseDeclared = SPDXID(p.opts.License)
+	}
+	if len(p.opts.Owners) > 0 {
+		if copyright, err := values.Fill("Copyright [year] [fullname]"); err == nil {
+			pkg.CopyrightText = copyright
+		}
+	}
+	doc.Relationships = append(doc.Relationships, SPDXRelationship{Element: doc.SPDXID, Type: "DESCRIBES", Related: pkg.SPDXID})
+
+	sort.Slice(result.Detections, func(i, j int) bool { return result.Detections[i].Path < result.Detections[j].Path })
+	fileLicenses := make(map[string]bool)
+	var sums []string
+	for i, detection := range result.Detections {
+		content, err := os.ReadFile(detection.Path)
+		if err != nil {
+			return nil, err
+		}
+		sha1Sum, sha256Sum := sha1.Sum(content), sha256.Sum256(content)
+		sums = append(sums, hex.EncodeToString(sha1Sum[:]))
+
+		rel, err := filepath.Rel(p.opts.Dir, detection.Path)
+		if err != nil {
+			rel = detection.Path
+		}
+		file := SPDXFile{
+			FileName: "./" + filepath.ToSlash(rel),
+			SPDXID:   fmt.Sprintf("SPDXRef-File-%d", i+1),
+			Checksums: []SPDXChecksum{
+				{Algorithm: "SHA1", Value: hex.EncodeToString(sha1Sum[:])},
+				{Algorithm: "SHA256", Value: hex.EncodeToString(sha256Sum[:])},
+			},
+			LicenseConcluded: "NOASSERTION",
+			CopyrightText:    "NONE",
+		}
+		switch detection.License {
+		case "":
+			file.LicenseInfoInFiles = []string{"NONE"}
+		case "unknown":
+			file.LicenseInfoInFiles = []string{"NOASSERTION"}
+		default:
+			names, err := licenseNames(detection.License)
+			if err != nil || len(names) == 0 {
+				names = []string{detection.License}
+			}
+			for _, name := range names {
+				file.LicenseInfoInFiles = append(file.LicenseInfoInFiles, SPDXID(name))
+				fileLicenses[SPDXID(name)] = true
+			}
+		}
+		if len(detection.Copyright) > 0 {
+			file.CopyrightText = strings.Join(detection.Copyright, "\n")
+		}
+		doc.Files = append(doc.Files, file)
+		doc.Relationships = append(doc.Relationships, SPDXRelationship{Element: pkg.SPDXID, Type: "CONTAINS", Related: file.SPDXID})
+	}
+
+	// The verification code covers the files in the order of their SHA-1
+	sort.Strings(sums)
+	verification := sha1.Sum([]byte(strings.Join(sums, "")))
+	pkg.VerificationCode.Value = hex.EncodeToString(verification[:])
+	pkg.LicenseInfoFromFiles = []string{"NONE"}
+	if len(fileLicenses) > 0 {
+		pkg.LicenseInfoFromFiles = nil
+		for license := range fileLicenses {
+			pkg.LicenseInfoFromFiles = append(pkg.LicenseInfoFromFiles, license)
+		}
+		sort.Strings(pkg.LicenseInfoFromFiles)
+	}
+	doc.Packages = []SPDXPackage{pkg}
+	return doc, nil
+}
@@ -0,0 +1,74 @@
+package licensed
+
+import (
+	"path"
+	"strings"
+)
+
+// interpreterExtensions maps the interpreters named by shebang lines to the
+// file extension whose comment syntax scripts run by them use
+var interpreterExtensions = map[string]string{
+	"sh":         ".sh",
+	"bash":       ".sh",
+	"dash":       ".sh",
+	"ksh":        ".sh",
+	"zsh":        ".sh",
+	"fish":       ".fish",
+	"python":     ".py",
+	"pypy":       ".py",
+	"ruby":       ".rb",
+	"perl":       ".pl",
+	"php":        ".php",
+	"lua":        ".lua",
+	"node":       ".js",
+	"nodejs":     ".js",
+	"deno":       ".ts",
+	"bun":        ".ts",
+	"ts-node":    ".ts",
+	"Rscript":    ".r",
+	"julia":      ".jl",
+	"pwsh":       ".ps1",
+	"elixir":     ".exs",
+	"escript":    ".erl",
+	"runghc":     ".hs",
+	"runhaskell": ".hs",
+	"ocaml":      ".ml",
+	"swift":      ".swift",
+	"kotlin":     ".kts",
+	"scala":      ".scala",
+	"groovy":     ".groovy",
+	"dart":       ".dart",
+	"make":       ".mk",
+	"awk":        ".sh",
+	"gawk":       ".sh",
+}
+
+// shebangExtension returns the file extension of the language of the script
+// at filePath according to the interpreter named by its shebang line, such
+// as .py for #!/usr/bin/env python3, or "" if there is none
+func shebangExtension(filePath string) string {
+	head, err := readHead(filePath, 256)
+	if err != nil || !strings.HasPrefix(head, "#!") {
+		return ""
+	}
+	line, _, _ := strings.Cut(head[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// Look past env and its options and variable assignments
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = path.Base(field)
+				break
+			}
+		}
+	}
+
+	// Drop versions such as python3.12
+	return interpreterExtensions[strings.TrimRight(interpreter, "0123456789.")]
+}
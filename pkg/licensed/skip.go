@@ -0,0 +1,47 @@
+package licensed
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// sniffLength is how much of a file is inspected to classify it
+const sniffLength = 8000
+
+// isBinaryFile reports whether the start of the file looks like binary data:
+// it contains a NUL byte or its sniffed MIME type is not textual. UTF-16
+// files with a byte order mark are text.
+func isBinaryFile(filePath string) (bool, error) {
+	buf, err := sniff(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	if isUTF16(sniffEncoding(buf)) {
+		return false, nil
+	}
+	if bytes.IndexByte(buf, 0) >= 0 {
+		return true, nil
+	}
+	contentType := http.DetectContentType(buf)
+	return !strings.HasPrefix(contentType, "text/") && !strings.Contains(contentType, "json") && !strings.Contains(contentType, "xml"), nil
+}
+
+// sniff returns the first sniffLength bytes of the file
+func sniff(filePath string) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return buf[:n], nil
+}
@@ -0,0 +1,122 @@
+package licensed
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Coverage counts the files eligible for a header and those carrying it
+type Coverage struct {
+	Files    int
+	Licensed int
+}
+
+// Percent returns the share of the files carrying the header, 100 if there
+// are none
+func (c Coverage) Percent() float64 {
+	if c.Files == 0 {
+		return 100
+	}
+	return 100 * float64(c.Licensed) / float64(c.Files)
+}
+
+func (c *Coverage) add(licensed bool) {
+	c.Files++
+	if licensed {
+		c.Licensed++
+	}
+}
+
+// Stats is the license header coverage of a project, in total and broken
+// down by directory and language
+type Stats struct {
+	Total Coverage
+	// Directories are keyed by the slash-separated path of the directory
+	// relative to the project directory, cut to the depth given to Stats,
+	// "." for files at the top
+	Directories map[string]*Coverage
+	// Languages are keyed by file extension without the dot, or by file
+	// name for well-known files without one such as Makefile
+	Languages map[string]*Coverage
+	// Result is that of the Check the stats were drawn from
+	Result *Result
+}
+
+// Stats runs Check quietly and reports how many of the files it covers
+// carry the expected header. Files skipped or failing to be read are not
+// eligible. Directories are broken down depth levels deep, at least one.
+func (p *Processor) Stats(depth int) (*Stats, error) {
+	checker := *p
+	checker.log = discardLogger
+	result, err := checker.Check()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{
+		Directories: make(map[string]*Coverage),
+		Languages:   make(map[string]*Coverage),
+		Result:      result,
+	}
+	count := func(files []string, licensed bool) {
+		for _, filePath := range files {
+			stats.Total.add(licensed)
+			coverageOf(stats.Directories, directoryAt(p.relPath(filePath), depth)).add(licensed)
+			coverageOf(stats.Languages, p.languageOf(filePath)).add(licensed)
+		}
+	}
+	count(result.Licensed, true)
+	count(result.Missing, false)
+	count(result.Modified, false)
+	return stats, nil
+}
+
+func coverageOf(coverages map[string]*Coverage, key string) *Coverage {
+	coverage, ok := coverages[key]
+	if !ok {
+		coverage = &Coverage{}
+		coverages[key] = coverage
+	}
+	return coverage
+}
+
+// directoryAt returns the directory of the slash-separated rel path cut to
+// depth levels, or "." for files at the top
+func directoryAt(rel string, depth int) string {
+	dir := path.Dir(rel)
+	if dir == "." {
+		return dir
+	}
+	parts := strings.Split(dir, "/")
+	if depth < 1 {
+		depth = 1
+	}
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+// languageOf names the language of filePath as commentSyntaxFor resolves
+// it: a well-known file name, the extension, or the extension of the
+// interpreter of its shebang
+func (p *Processor) languageOf(filePath string) string {
+	name := strings.ToLower(filepath.Base(filePath))
+	switch {
+	case isDockerfile(filePath):
+		return "dockerfile"
+	case isNotebook(filePath):
+		return "ipynb"
+	}
+	if _, ok := p.commentSyntaxes[name]; ok {
+		return name
+	}
+	if ext := strings.ToLower(filepath.Ext(filePath)); ext != "" {
+		return strings.TrimPrefix(ext, ".")
+	}
+	if ext := shebangExtension(filePath); ext != "" {
+		return strings.TrimPrefix(ext, ".")
+	}
+	return name
+}
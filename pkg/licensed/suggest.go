@@ -0,0 +1,92 @@
+package licensed
+
+import (
+	"strings"
+)
+
+// UnknownLicenseError is returned for a license that is neither in the
+// license directory, the bundled catalog nor the download cache, and could
+// not be downloaded
+type UnknownLicenseError struct {
+	Name string
+	// Suggestion is the known license closest to Name, if any is close
+	Suggestion string
+	// Available are the names of the bundled licenses and those in the
+	// license directory
+	Available []string
+	// Err is why the license could not be downloaded, if it may exist
+	Err error
+}
+
+func (e *UnknownLicenseError) Error() string {
+	msg := "unknown license " + e.Name
+	if e.Suggestion != "" {
+		msg += ", did you mean " + e.Suggestion + "?"
+	}
+	if e.Err != nil {
+		msg += " (" + e.Err.Error() + ")"
+	}
+	return msg
+}
+
+func (e *UnknownLicenseError) Unwrap() error {
+	return e.Err
+}
+
+// unknownLicense describes the license name that could not be found, err
+// being the download error
+func (p *Processor) unknownLicense(name string, err error) *UnknownLicenseError {
+	unknown := &UnknownLicenseError{Name: name}
+	if _, ok := err.(*UnknownLicenseError); !ok {
+		unknown.Err = err
+	}
+	unknown.Available, _ = AvailableLicenses(p.opts.LicenseDir)
+	unknown.Suggestion = suggestLicense(name, unknown.Available)
+	return unknown
+}
+
+// suggestLicense returns the license of names, or of the catalog by SPDX
+// identifier, that name is most likely a typo of, or "" if none is close
+func suggestLicense(name string, names []string) string {
+	name = strings.ToLower(name)
+	candidates := make(map[string]string)
+	for _, candidate := range names {
+		candidates[strings.ToLower(candidate)] = candidate
+	}
+	for catalogName, info := range catalog {
+		candidates[strings.ToLower(info.SPDXID)] = catalogName
+	}
+
+	// Allow about one typo per three characters
+	best, bestDistance := "", max(1, len(name)/3)+1
+	for candidate, suggestion := range candidates {
+		distance := levenshtein(name, candidate)
+		if distance < bestDistance || distance == bestDistance && suggestion < best {
+			best, bestDistance = suggestion, distance
+		}
+	}
+	return best
+}
+
+// levenshtein returns the number of single character insertions, deletions
+// and substitutions turning a into b
+func levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur := make([]int, len(t)+1)
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(t)]
+}
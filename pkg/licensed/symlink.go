@@ -0,0 +1,38 @@
+package licensed
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// fileKey returns a key telling apart the files reachable through several
+// paths, such as symbolic or hard links: the inode where available,
+// otherwise the path with all links resolved
+func fileKey(filePath string) (any, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := inode(info); ok {
+		return id, nil
+	}
+	realPath, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return filepath.Abs(realPath)
+}
+
+// visitOnce reports whether filePath is seen for the first time, recording
+// it in seen. Files whose identity cannot be told are always visited.
+func visitOnce(seen map[any]bool, filePath string) bool {
+	key, err := fileKey(filePath)
+	if err != nil {
+		return true
+	}
+	if seen[key] {
+		return false
+	}
+	seen[key] = true
+	return true
+}
@@ -0,0 +1,168 @@
+package licensed
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData holds the variables available to header templates, e.g.
+// {{.Year}} or {{.Owner}}
+type TemplateData struct {
+	// Year is the copyright year or year range
+	Year string
+	// Owner is the names of the copyright holders, separated by commas
+	Owner string
+	// Owners are the copyright holders, each with its own year if set
+	Owners []Owner
+	// License is the license name, e.g. "mit"
+	License string
+	// SPDXID is the SPDX identifier of the license, e.g. "MIT"
+	SPDXID string
+	// Email is the email of the first copyright holder
+	Email string
+	// Project is the project name, by default that of the project directory
+	Project string
+	// Organization is the organization behind the project
+	Organization string
+	// URL is the project URL
+	URL string
+	// Filename is the base name of the file receiving the header
+	Filename string
+}
+
+// templateFuncs returns the functions available to header templates besides
+// those of text/template:
+//
+//   - now is the time of the run, e.g. {{ now.Year }} or
+//     {{ now.Format "2006-01-02" }}
+//   - gitUser is the git user.name of the project
+//   - relpath is the slash-separated path of the file receiving the header,
+//     relative to the project directory
+//   - env is the value of an environment variable, e.g. {{ env "ORG_NAME" }}
+func (p *Processor) templateFuncs() template.FuncMap {
+	now := time.Now()
+	var gitUser *string
+	return template.FuncMap{
+		"now": func() time.Time { return now },
+		"gitUser": func() string {
+			if gitUser == nil {
+				name := GitUserName(p.opts.Dir)
+				gitUser = &name
+			}
+			return *gitUser
+		},
+		"relpath": func() string { return "" },
+		"env":     os.Getenv,
+	}
+}
+
+// headerRenderer renders the header text of each file from the header
+// template of a run
+type headerRenderer struct {
+	text   string
+	tmpl   *template.Template
+	data   TemplateData
+	values Placeholders
+	// checksum appends the checksum marker to the rendered header
+	checksum bool
+	// verbatim leaves the placeholders of a HeaderFile header unfilled
+	verbatim bool
+	// relPath returns the path of a file as the relpath function does
+	relPath func(filePath string) string
+}
+
+// newHeaderRenderer reads the header text selected by opts, parsing it as a
+// text/template when it comes from a Template file
+func (p *Processor) newHeaderRenderer(opts Options) (*headerRenderer, error) {
+	text, err := p.headerTemplate(opts)
+	if err != nil {
+		return nil, fmt.Errorf("reading license: %w", err)
+	}
+
+	values := p.placeholders()
+	values.Owners = opts.Owners
+	r := &headerRenderer{
+		text:     text,
+		checksum: opts.Checksum,
+		verbatim: opts.HeaderFile != "",
+		relPath:  p.relPath,
+		values:   values,
+		data: TemplateData{
+			Owner:        ownerNames(opts.Owners),
+			Owners:       opts.Owners,
+			License:      opts.License,
+			SPDXID:       SPDXID(opts.License),
+			Project:      values.Project,
+			Organization: values.Organization,
+			URL:          values.URL,
+		},
+	}
+	if len(opts.Owners) > 0 {
+		r.data.Email = opts.Owners[0].Email
+	}
+
+	// Bundled license texts are plain text, only templates are executed
+	if opts.Template != "" {
+		r.tmpl, err = template.New(filepath.Base(opts.Template)).Funcs(p.templateFuncs()).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// render returns the header text of filePath with the template variables
+// and placeholders filled in, followed by the
+// checksum marker with Checksum
+func (r *headerRenderer) render(filePath, year string) (string, error) {
+	text := r.text
+	if r.tmpl != nil {
+		data := r.data
+		data.Year = year
+		data.Filename = filepath.Base(filePath)
+
+		// relpath is bound to the file on a copy of the template
+		tmpl, err := r.tmpl.Clone()
+		if err != nil {
+			return "", err
+		}
+		tmpl.Funcs(template.FuncMap{"relpath": func() string {
+			if filePath == "" {
+				return ""
+			}
+			return r.relPath(filePath)
+		}})
+
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("executing template for %s: %w", filePath, err)
+		}
+		text = buf.String()
+	}
+	if !r.verbatim {
+		values := r.values
+		values.Year = year
+		filled, err := values.Fill(text)
+		if err != nil {
+			return "", fmt.Errorf("rendering header: %w", err)
+		}
+		text = filled
+	}
+	if r.checksum {
+		text = withChecksum(text)
+	}
+	return text, nil
+}
+
+// ownerNames joins the names of owners with commas
+func ownerNames(owners []Owner) string {
+	names := make([]string, len(owners))
+	for i, owner := range owners {
+		names[i] = owner.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,103 @@
+package licensed
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// copyrightYears matches the years of a copyright notice: after
+// "Copyright" with an optional "(c)" or "©", after an SPDX-FileCopyrightText
+// tag, or after a lone "©" or "(C)". The years are a comma-separated list of
+// years and ranges, which may wrap onto the next comment line.
+var copyrightYears = regexp.MustCompile(`(?i)((?:copyright|spdx-filecopyrighttext:)\s*(?:\(c\)|©)?\s*|©\s*|\(c\)\s*)(\d{4}(?:\s*[-–]\s*(?:\d{4}|present))?(?:,(?:\s|//|#|\*|;)*\d{4}(?:\s*[-–]\s*(?:\d{4}|present))?)*)`)
+
+// lastYears matches the last year or range of a list of copyright years
+var lastYears = regexp.MustCompile(`(?i)(\d{4})(?:(\s*[-–]\s*)(\d{4}|present))?$`)
+
+// Update bumps the copyright years in the license header of every file of
+// the project so they run up to target
+func (p *Processor) Update(target int) (*Result, error) {
+	unlock, err := p.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result := &Result{}
+	err = p.forEachFile(result, func(filePath string) error {
+		commentSyntax, ok := p.commentSyntaxFor(filePath)
+		if !ok {
+			p.skipFile(result, "unknown type", filePath)
+			return nil
+		}
+
+		changed, err := p.UpdateCopyrightYears(filePath, commentSyntax, target)
+		if err != nil {
+			return err
+		}
+		if changed {
+			result.Changed = append(result.Changed, filePath)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// UpdateCopyrightYears extends the copyright years in the file's license
+// header to include target, reporting whether the file was changed
+func (p *Processor) UpdateCopyrightYears(filePath string, commentSyntax CommentSyntax, target int) (bool, error) {
+	// Read the existing file content
+	content, err := readText(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	// Only touch the comment block at the top of the file
+	bom, text := splitBOM(content)
+	preamble, rest := splitPreamble(text, filePath)
+	if !looksLikeLicense(rest[:leadingCommentLength(rest, commentSyntax)]) {
+		// The header may follow a file-level docstring
+		doc, afterDoc := splitDocstring(rest, filePath)
+		preamble, rest = preamble+doc, afterDoc
+	}
+	end := leadingCommentLength(rest, commentSyntax)
+	header := rest[:end]
+	if !looksLikeLicense(header) {
+		return false, nil
+	}
+
+	newHeader := bumpCopyrightYears(header, target)
+	if newHeader == header {
+		return false, nil
+	}
+	return true, p.writeFile(filePath, content, bom+preamble+newHeader+rest[end:])
+}
+
+// bumpCopyrightYears rewrites the copyright notices in header so their
+// years run up to target, extending the last year or range of each list:
+// "2019, 2021-2023" becomes "2019, 2021-<target>"
+func bumpCopyrightYears(header string, target int) string {
+	return copyrightYears.ReplaceAllStringFunc(header, func(match string) string {
+		groups := copyrightYears.FindStringSubmatch(match)
+		years := groups[2]
+		last := lastYears.FindStringSubmatchIndex(years)
+		if last == nil {
+			return match
+		}
+		start, _ := strconv.Atoi(years[last[2]:last[3]])
+		end, dash := start, "-"
+		if last[4] >= 0 {
+			dash = years[last[4]:last[5]]
+			if strings.EqualFold(years[last[6]:last[7]], "present") {
+				return match
+			}
+			end, _ = strconv.Atoi(years[last[6]:last[7]])
+		}
+		if end >= target {
+			return match
+		}
+		return groups[1] + years[:last[2]] + fmt.Sprintf("%d%s%d", start, dash, target)
+	})
+}
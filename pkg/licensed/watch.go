@@ -0,0 +1,152 @@
+package licensed
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch adds the license header to the files created in the project
+// directory while it runs, until ctx is done. Every directory not skipped by
+// Add is watched for new files, skipping ignored files as Add does. Creating
+// or writing a new file restarts a debounce timer of interval, and the new
+// files are only stamped once it fires, so that editors and generators can
+// finish writing them. The result of every batch of stamped files is passed
+// to report.
+func (p *Processor) Watch(ctx context.Context, interval time.Duration, report func(*Result)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	gitignores := newIgnoreTree(p.opts.Dir, ".gitignore")
+	pending := make(map[string]bool)
+	if err := p.watchDir(watcher, gitignores, p.opts.Dir, nil); err != nil {
+		return err
+	}
+
+	debounce := time.NewTimer(interval)
+	debounce.Stop()
+	defer debounce.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// Events below a project directory of . start with ./
+			event.Name = filepath.Clean(event.Name)
+			switch {
+			case event.Has(fsnotify.Create):
+				info, err := os.Lstat(event.Name)
+				if err != nil {
+					continue
+				}
+				if info.IsDir() {
+					// Files may be created before the new directory is
+					// watched, they are picked up by walking it
+					if p.skipWatchDir(gitignores, event.Name, info) {
+						continue
+					}
+					if err := p.watchDir(watcher, gitignores, event.Name, pending); err != nil {
+						return err
+					}
+				} else if p.watchesFile(gitignores, event.Name) {
+					pending[event.Name] = true
+				}
+			case event.Has(fsnotify.Write):
+				if !pending[event.Name] {
+					// Only new files are stamped, not edited ones
+					continue
+				}
+			case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+				// Files that were deleted count as new if they are created
+				// again
+				delete(pending, event.Name)
+				continue
+			default:
+				continue
+			}
+			if len(pending) > 0 {
+				debounce.Reset(interval)
+			}
+		case <-debounce.C:
+			var ready []string
+			for filePath := range pending {
+				if info, err := os.Stat(filePath); err == nil && info.Mode().IsRegular() {
+					ready = append(ready, filePath)
+				}
+			}
+			clear(pending)
+			if len(ready) == 0 {
+				continue
+			}
+
+			sort.Strings(ready)
+			// A handful of new files needs no progress indicator
+			stamper := *p
+			stamper.opts.Files = ready
+			stamper.opts.Progress = nil
+			result, err := stamper.Add()
+			if err != nil {
+				return err
+			}
+			report(result)
+		}
+	}
+}
+
+// watchDir adds dir and the directories below it not skipped by Add to
+// watcher. The files found are added to pending unless it is nil.
+func (p *Processor) watchDir(watcher *fsnotify.Watcher, gitignores *ignoreTree, dir string, pending map[string]bool) error {
+	return filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Directories removed or unreadable in the meantime are left out
+			if filePath == p.opts.Dir {
+				return err
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			info, err := entry.Info()
+			if err != nil {
+				return filepath.SkipDir
+			}
+			if filePath != p.opts.Dir && p.skipWatchDir(gitignores, filePath, info) {
+				return filepath.SkipDir
+			}
+			return watcher.Add(filePath)
+		}
+		if pending != nil && entry.Type().IsRegular() && p.watchesFile(gitignores, filePath) {
+			pending[filePath] = true
+		}
+		return nil
+	})
+}
+
+// skipWatchDir reports whether the directory is skipped by the walk of Add
+func (p *Processor) skipWatchDir(gitignores *ignoreTree, dir string, info fs.FileInfo) bool {
+	return isReparsePoint(info) || p.isDefaultSkippedDir(p.relPath(dir)) || (!p.opts.IncludeHidden && isHiddenName(info.Name())) || p.shouldSkipDir(dir) || (!p.opts.NoGitignore && gitignores.ignored(dir, true))
+}
+
+// watchesFile reports whether a new file is stamped, i.e. not ruled out by
+// the ignore and include patterns
+func (p *Processor) watchesFile(gitignores *ignoreTree, filePath string) bool {
+	if p.isCacheFile(filePath) || p.shouldIgnoreFile(filePath) || !p.isIncluded(filePath) || (!p.opts.IncludeHidden && p.isHidden(filePath)) {
+		return false
+	}
+	return p.opts.NoGitignore || !gitignores.ignored(filePath, false)
+}
@@ -0,0 +1,148 @@
+package licensed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrFileChanged is returned when a file changed between being read and
+// being replaced, e.g. by an editor or another run, which is left alone
+var ErrFileChanged = errors.New("file changed since it was read")
+
+// ErrReadOnly is returned for files that are read-only, e.g. not opened for
+// edit in Perforce, or that the system refuses to replace, e.g. immutable or
+// locked ones. They are left alone and reported in Result.ReadOnly.
+var ErrReadOnly = errors.New("file is read-only")
+
+// makeWritable fails with ErrReadOnly if filePath is read-only, unless
+// ForceWritable is set, in which case it grants write permission until the
+// returned function restores the original permissions
+func (p *Processor) makeWritable(filePath string) (func(), error) {
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return func() {}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	mode := info.Mode().Perm()
+	if mode&0200 != 0 {
+		return func() {}, nil
+	}
+	if !p.opts.ForceWritable {
+		return nil, ErrReadOnly
+	}
+	if err := os.Chmod(filePath, mode|0200); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReadOnly, err)
+	}
+	return func() {
+		if err := os.Chmod(filePath, mode); err != nil {
+			p.log.Warn("cannot restore permissions", "path", filePath, "mode", mode, "error", err)
+		}
+	}, nil
+}
+
+// writeError tells a failure to replace a file for lack of permission, as
+// for immutable or locked files, as ErrReadOnly
+func writeError(err error) error {
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("%w: %w", ErrReadOnly, err)
+	}
+	return err
+}
+
+// AtomicWriteFile replaces the content of path with data through a
+// temporary file renamed over it, so that a crash never leaves a partially
+// written file. An existing file keeps its permissions and ownership, and
+// is not touched at all if its content is already data; perm only applies
+// to new files.
+func AtomicWriteFile(path string, data []byte, perm os.FileMode) error {
+	// Write through symlinks rather than replacing them
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	existing, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	return atomicWriteStream(path, perm, nil, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// atomicWriteStream is AtomicWriteFile for content produced by write, which
+// may read the file being replaced. The file is replaced even if write
+// reproduces its content. If given, verify is called on the file right
+// before it is replaced and aborts the write with an error.
+func atomicWriteStream(path string, perm os.FileMode, verify func(path string) error, write func(w io.Writer) error) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		perm = info.Mode().Perm()
+	case os.IsNotExist(err):
+		info = nil
+	default:
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".licensed-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if info != nil {
+		if err := copyOwner(tmp.Name(), info); err != nil {
+			return err
+		}
+	}
+	if verify != nil {
+		if err := verify(path); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// unchangedSince returns a check that path still holds content, or does not
+// exist if content is empty, failing with ErrFileChanged otherwise
+func unchangedSince(content string) func(path string) error {
+	return func(path string) error {
+		current, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) && content == "" {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if string(current) != content {
+			return ErrFileChanged
+		}
+		return nil
+	}
+}